@@ -2,6 +2,16 @@ package client
 
 import "time"
 
+const (
+	// DefaultMinBackoff is the base delay used by strategies
+	// initialised with a non-positive delay.
+	DefaultMinBackoff = 100 * time.Millisecond
+
+	// DefaultMaxBackoff is the delay cap used by strategies
+	// initialised with a non-positive max.
+	DefaultMaxBackoff = 30 * time.Second
+)
+
 // Backoff defines the contract for various retry delay strategies.
 // It encapsulates the logic for calculating wait times between retries.
 type Backoff interface {
@@ -12,9 +22,32 @@ type Backoff interface {
 	//     it represents the current retry attempt number (0, 1, 2...).
 	//   - For state-aware strategies (DecorrelatedJitter), it represents the
 	//     previous delay duration in nanoseconds (time.Duration.Nanoseconds()).
+	//     Such strategies also implement StatefulBackoff.
 	//   - For static strategies (Fixed), the argument is ignored.
 	//
 	// The returned duration is guaranteed to be capped by the maxDelay
 	// configured during the strategy's initialization.
 	Next(arg int64) time.Duration
 }
+
+// StatefulBackoff is implemented by strategies whose next delay depends on the
+// previous one rather than on the attempt number, such as DecorrelatedJitter.
+// Callers that keep track of the previous delay, like the Client retries and
+// the pool's cooldown escalation, call NextAfter instead of Next.
+type StatefulBackoff interface {
+	Backoff
+
+	// NextAfter calculates the delay following previous, which is zero
+	// before the first delay.
+	NextAfter(previous time.Duration) time.Duration
+}
+
+// nextDelay returns the delay backoff gives for the attempt number attempt,
+// previous being the delay it returned for the attempt before.
+func nextDelay(backoff Backoff, attempt int64, previous time.Duration) time.Duration {
+	if stateful, ok := backoff.(StatefulBackoff); ok {
+		return stateful.NextAfter(previous)
+	}
+
+	return backoff.Next(attempt)
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// banditPendingDecay is the time constant with which UCB1 forgets picks that
+// never recorded a result.
+const banditPendingDecay = 10 * time.Second
+
+// BanditStrategy selects the multi-armed bandit algorithm used by BanditSelector.
+type BanditStrategy int
+
+const (
+	// ThompsonSampling draws a plausible success rate for every entry from its
+	// Beta posterior and picks the highest draw. Uncertain entries produce widely
+	// spread draws and therefore win from time to time, which is what explores them.
+	ThompsonSampling BanditStrategy = iota
+
+	// UCB1 picks the entry with the highest upper confidence bound on its success
+	// rate. Untried entries have an infinite bound and are always tried first.
+	// Picks still waiting for a result count as tries in the confidence term, so
+	// concurrent callers spread over the entries instead of all taking the same one.
+	UCB1
+)
+
+// BanditSelector treats proxy selection as a multi-armed bandit problem:
+// every entry is an arm whose reward is a successful request.
+//
+// Compared to WeightedSelector, whose successRate*1000/latency score hands a
+// brand-new proxy a weight of 1.0 against established proxies scoring hundreds,
+// a bandit explicitly balances exploring proxies it knows little about against
+// exploiting those that are known to be good.
+//
+// Successes and failures are read from the entry's recent Stats window, so old
+// evidence is forgotten and a proxy that changed behaviour is re-evaluated.
+//
+// When latencyWeight is positive the reward is discounted by latency:
+//
+//	score = reward / (1 + latencyWeight * avgLatencySeconds)
+//
+// BanditSelector is safe for concurrent use.
+type BanditSelector struct {
+	strategy      BanditStrategy
+	latencyWeight float64
+
+	mutex  sync.Mutex
+	random *rand.Rand
+
+	// inflight holds the picks without a result per entry, for UCB1 only.
+	inflight map[*Entry]*inFlight
+}
+
+// NewBanditSelector initializes a new selector using the given strategy,
+// seeded from a random source. A non-positive latencyWeight ignores latency.
+func NewBanditSelector(strategy BanditStrategy, latencyWeight float64) *BanditSelector {
+	return NewSeededBanditSelector(strategy, latencyWeight, rand.Uint64())
+}
+
+// NewSeededBanditSelector initializes a new selector whose random decisions
+// are fully determined by seed. Intended for reproducible tests and simulations.
+func NewSeededBanditSelector(strategy BanditStrategy, latencyWeight float64, seed uint64) *BanditSelector {
+	return &BanditSelector{
+		strategy:      strategy,
+		latencyWeight: max(latencyWeight, 0),
+		random:        rand.New(rand.NewPCG(seed, seed)),
+		inflight:      make(map[*Entry]*inFlight),
+	}
+}
+
+// Select returns the entry with the highest score under the configured strategy.
+// Ties are resolved at random.
+func (b *BanditSelector) Select(entries []*Entry) *Entry {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	totals := make([]windowTotals, len(entries))
+
+	for i, entry := range entries {
+		totals[i] = entry.stats.recent.totals(now)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	pending := make([]float64, len(entries))
+	if b.strategy == UCB1 {
+		for i, entry := range entries {
+			pending[i] = b.pending(entry).count(entry, now)
+		}
+	}
+
+	var plays float64
+	for i := range entries {
+		plays += float64(totals[i].success+totals[i].failures) + pending[i]
+	}
+
+	best, bestScore, ties := 0, math.Inf(-1), 0
+	for i, entry := range entries {
+		var score float64
+
+		switch b.strategy {
+		case UCB1:
+			score = ucb1Score(totals[i], pending[i], plays)
+		default:
+			score = betaSample(b.random, float64(1+totals[i].success), float64(1+totals[i].failures))
+		}
+
+		if b.latencyWeight > 0 {
+			score /= 1 + b.latencyWeight*entry.stats.RecentAvgLatencyMs()/1000
+		}
+
+		switch {
+		case score > bestScore:
+			best, bestScore, ties = i, score, 1
+		case score == bestScore:
+			// Reservoir sampling keeps every tied entry equally likely.
+			ties++
+			if b.random.IntN(ties) == 0 {
+				best = i
+			}
+		}
+	}
+
+	if b.strategy == UCB1 {
+		b.pending(entries[best]).add(now)
+	}
+
+	return entries[best]
+}
+
+func (b *BanditSelector) forget(entry *Entry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.inflight, entry)
+}
+
+// pending returns the in-flight estimate of entry. It must be called with b.mutex held.
+func (b *BanditSelector) pending(entry *Entry) *inFlight {
+	inflight, ok := b.inflight[entry]
+	if !ok {
+		created := newInFlight(entry, banditPendingDecay)
+		inflight = &created
+		b.inflight[entry] = inflight
+	}
+
+	return inflight
+}
+
+// ucb1Score returns mean + sqrt(2 ln N / n), where n counts the arm's results and
+// pending picks and N those of all arms, or +Inf for an arm that was never played.
+// An arm whose picks are all still pending is assumed to succeed.
+func ucb1Score(totals windowTotals, pending, plays float64) float64 {
+	tries := totals.success + totals.failures
+
+	n := float64(tries) + pending
+	if n == 0 {
+		return math.Inf(1)
+	}
+
+	mean := 1.0
+	if tries > 0 {
+		mean = float64(totals.success) / float64(tries)
+	}
+
+	return mean + math.Sqrt(2*math.Log(max(plays, 1))/n)
+}
+
+// betaSample draws from Beta(alpha, beta) as X / (X + Y) with X ~ Gamma(alpha), Y ~ Gamma(beta).
+func betaSample(random *rand.Rand, alpha, beta float64) float64 {
+	x := gammaSample(random, alpha)
+	y := gammaSample(random, beta)
+
+	return x / (x + y)
+}
+
+// gammaSample draws from Gamma(shape, 1) for shape >= 1
+// using the Marsaglia–Tsang method.
+func gammaSample(random *rand.Rand, shape float64) float64 {
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+
+	for {
+		x := random.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+
+		v = v * v * v
+		u := random.Float64()
+
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandit(t *testing.T) {
+	t.Parallel()
+
+	record := func(entry *Entry, success, failures int) {
+		for i := 0; i < success; i++ {
+			entry.stats.RecordSuccess()
+		}
+
+		for i := 0; i < failures; i++ {
+			entry.stats.RecordFailed()
+		}
+	}
+
+	t.Run("EmptyEntries", func(t *testing.T) {
+		assert.Nil(t, NewBanditSelector(ThompsonSampling, 0).Select(nil))
+		assert.Nil(t, NewBanditSelector(UCB1, 0).Select(nil))
+	})
+
+	t.Run("SeededSelectorIsDeterministic", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2}), newEntry(&mockProxy{id: 3})}
+
+		first := NewSeededBanditSelector(ThompsonSampling, 0, 42)
+		second := NewSeededBanditSelector(ThompsonSampling, 0, 42)
+
+		for i := 0; i < 50; i++ {
+			assert.Equal(t, first.Select(entries), second.Select(entries))
+		}
+	})
+
+	t.Run("ThompsonExploitsGoodProxy", func(t *testing.T) {
+		good := newEntry(&mockProxy{id: 1})
+		bad := newEntry(&mockProxy{id: 2})
+		record(good, 90, 10)
+		record(bad, 10, 90)
+
+		selector := NewSeededBanditSelector(ThompsonSampling, 0, 1)
+		entries := []*Entry{good, bad}
+
+		counts := make(map[*Entry]int)
+		for i := 0; i < 1000; i++ {
+			counts[selector.Select(entries)]++
+		}
+
+		assert.Equal(t, 1000, counts[good])
+	})
+
+	t.Run("ThompsonExploresNewProxy", func(t *testing.T) {
+		established := newEntry(&mockProxy{id: 1})
+		record(established, 70, 30)
+		fresh := newEntry(&mockProxy{id: 2})
+
+		selector := NewSeededBanditSelector(ThompsonSampling, 0, 7)
+		entries := []*Entry{established, fresh}
+
+		counts := make(map[*Entry]int)
+		for i := 0; i < 1000; i++ {
+			counts[selector.Select(entries)]++
+		}
+
+		// With a uniform prior the new proxy beats a 0.7 success rate about 30% of the time.
+		assert.InDelta(t, 300, counts[fresh], 60)
+	})
+
+	t.Run("UCB1TriesUnplayedEntriesFirst", func(t *testing.T) {
+		played := newEntry(&mockProxy{id: 1})
+		record(played, 100, 0)
+		unplayed := newEntry(&mockProxy{id: 2})
+
+		selector := NewBanditSelector(UCB1, 0)
+		assert.Equal(t, unplayed, selector.Select([]*Entry{played, unplayed}))
+	})
+
+	t.Run("UCB1BalancesConfidence", func(t *testing.T) {
+		wellKnown := newEntry(&mockProxy{id: 1})
+		record(wellKnown, 800, 200)
+
+		barelyKnown := newEntry(&mockProxy{id: 2})
+		record(barelyKnown, 1, 1)
+
+		selector := NewBanditSelector(UCB1, 0)
+		assert.Equal(t, barelyKnown, selector.Select([]*Entry{wellKnown, barelyKnown}))
+
+		record(barelyKnown, 40, 160)
+		assert.Equal(t, wellKnown, selector.Select([]*Entry{wellKnown, barelyKnown}))
+	})
+
+	t.Run("UCB1SpreadsPendingPicks", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2}), newEntry(&mockProxy{id: 3})}
+		selector := NewBanditSelector(UCB1, 0)
+
+		// No results are recorded in between, as with concurrent callers.
+		counts := make(map[*Entry]int)
+		for i := 0; i < 30; i++ {
+			counts[selector.Select(entries)]++
+		}
+
+		for _, entry := range entries {
+			assert.Equal(t, 10, counts[entry])
+		}
+	})
+
+	t.Run("UCB1BreaksTiesAtRandom", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2})}
+		record(entries[0], 5, 5)
+		record(entries[1], 5, 5)
+
+		counts := make(map[*Entry]int)
+		for seed := uint64(0); seed < 100; seed++ {
+			counts[NewSeededBanditSelector(UCB1, 0, seed).Select(entries)]++
+		}
+
+		assert.InDelta(t, 50, counts[entries[0]], 20)
+		assert.InDelta(t, 50, counts[entries[1]], 20)
+	})
+
+	t.Run("UCB1ForgetsRemovedEntries", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+		selector := NewBanditSelector(UCB1, 0)
+
+		selector.Select([]*Entry{entry})
+		assert.InDelta(t, 1.0, selector.pending(entry).count(entry, time.Now()), 0.01)
+
+		selector.forget(entry)
+		assert.Empty(t, selector.inflight)
+	})
+
+	t.Run("LatencyWeightPenalizesSlowProxy", func(t *testing.T) {
+		fast := newEntry(&mockProxy{id: 1})
+		record(fast, 49, 1)
+		fast.stats.RecordLatency(50 * time.Millisecond)
+
+		slow := newEntry(&mockProxy{id: 2})
+		record(slow, 50, 0)
+		slow.stats.RecordLatency(2 * time.Second)
+
+		entries := []*Entry{slow, fast}
+
+		assert.Equal(t, slow, NewBanditSelector(UCB1, 0).Select(entries))
+		assert.Equal(t, fast, NewBanditSelector(UCB1, 1).Select(entries))
+	})
+
+	t.Run("BetaSampleMean", func(t *testing.T) {
+		random := rand.New(rand.NewPCG(3, 3))
+
+		var sum float64
+		for i := 0; i < 20000; i++ {
+			sample := betaSample(random, 3, 7)
+			assert.True(t, sample >= 0 && sample <= 1)
+			sum += sample
+		}
+
+		assert.InDelta(t, 0.3, sum/20000, 0.01)
+	})
+}
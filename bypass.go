@@ -0,0 +1,147 @@
+package client
+
+import (
+	"net"
+	"strings"
+)
+
+// BypassRules decides which target hosts must be reached directly rather than
+// through a proxy. The syntax follows the NO_PROXY environment variable as
+// interpreted by net/http:
+//
+//   - "*" matches every host;
+//   - an IP address ("10.0.0.1", "::1") matches that address;
+//   - a CIDR block ("10.0.0.0/8") matches any IP address inside it;
+//   - a domain ("example.com") matches the domain and all of its subdomains;
+//   - a domain with a leading dot (".example.com") or "*." ("*.example.com")
+//     matches subdomains only;
+//   - any entry except CIDR blocks may end with ":port" to match that port only.
+//
+// Hostnames are not resolved: IP and CIDR rules only match targets given as IP literals.
+// Rules read from NO_PROXY by ProxyFromEnvironment also match localhost and every
+// loopback address, as net/http does.
+type BypassRules struct {
+	all      bool
+	loopback bool
+	ips      []bypassIP
+	cidrs    []*net.IPNet
+	domains  []bypassDomain
+}
+
+type bypassIP struct {
+	ip   net.IP
+	port string
+}
+
+type bypassDomain struct {
+	// suffix is the lower-cased domain with a leading dot.
+	suffix string
+
+	// exact also matches the domain itself, not only its subdomains.
+	exact bool
+	port  string
+}
+
+// ParseBypassRules parses rules separated by commas and/or whitespace.
+// Empty entries are ignored.
+func ParseBypassRules(rules string) *BypassRules {
+	bypass := &BypassRules{}
+
+	fields := strings.FieldsFunc(rules, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' })
+	for _, field := range fields {
+		bypass.add(strings.ToLower(strings.TrimSpace(field)))
+	}
+
+	return bypass
+}
+
+func (b *BypassRules) add(rule string) {
+	if rule == "*" {
+		b.all = true
+		return
+	}
+
+	if _, cidr, err := net.ParseCIDR(rule); err == nil {
+		b.cidrs = append(b.cidrs, cidr)
+		return
+	}
+
+	host, port := rule, ""
+	if h, p, err := net.SplitHostPort(rule); err == nil {
+		host, port = h, p
+	}
+
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		b.ips = append(b.ips, bypassIP{ip: ip, port: port})
+		return
+	}
+
+	host = strings.TrimPrefix(host, "*")
+	exact := !strings.HasPrefix(host, ".")
+	if !strings.HasPrefix(host, ".") {
+		host = "." + host
+	}
+
+	b.domains = append(b.domains, bypassDomain{suffix: host, exact: exact, port: port})
+}
+
+// Match reports whether a request to host:port should bypass proxies.
+// host may be a hostname or an IP literal; port may be empty.
+func (b *BypassRules) Match(host, port string) bool {
+	if b == nil {
+		return false
+	}
+
+	if b.all {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+
+	if b.loopback && isLoopback(host) {
+		return true
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, rule := range b.ips {
+			if rule.ip.Equal(ip) && (rule.port == "" || rule.port == port) {
+				return true
+			}
+		}
+
+		for _, cidr := range b.cidrs {
+			if cidr.Contains(ip) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, rule := range b.domains {
+		if rule.port != "" && rule.port != port {
+			continue
+		}
+
+		if strings.HasSuffix(host, rule.suffix) || (rule.exact && host == rule.suffix[1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Empty reports whether no rules were configured.
+func (b *BypassRules) Empty() bool {
+	return b == nil || (!b.all && !b.loopback && len(b.ips) == 0 && len(b.cidrs) == 0 && len(b.domains) == 0)
+}
+
+// isLoopback reports whether host, in lower case, is localhost or a loopback IP address.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
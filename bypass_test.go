@@ -0,0 +1,85 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBypassRules(t *testing.T) {
+	t.Parallel()
+
+	t.Run("EmptyRules", func(t *testing.T) {
+		rules := ParseBypassRules(" , ")
+		assert.True(t, rules.Empty())
+		assert.False(t, rules.Match("example.com", "443"))
+
+		var nilRules *BypassRules
+		assert.True(t, nilRules.Empty())
+		assert.False(t, nilRules.Match("example.com", "443"))
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		rules := ParseBypassRules("*")
+		assert.False(t, rules.Empty())
+		assert.True(t, rules.Match("anything.example", "80"))
+	})
+
+	cases := []struct {
+		name  string
+		rules string
+		host  string
+		port  string
+		match bool
+	}{
+		{name: "DomainMatchesItself", rules: "example.com", host: "example.com", port: "443", match: true},
+		{name: "DomainMatchesSubdomain", rules: "example.com", host: "api.example.com", port: "443", match: true},
+		{name: "DomainDoesNotMatchSuffixOfLabel", rules: "example.com", host: "badexample.com", port: "443", match: false},
+		{name: "LeadingDotSkipsDomainItself", rules: ".example.com", host: "example.com", port: "443", match: false},
+		{name: "LeadingDotMatchesSubdomain", rules: ".example.com", host: "a.example.com", port: "443", match: true},
+		{name: "LeadingWildcardDot", rules: "*.example.com", host: "a.example.com", port: "443", match: true},
+		{name: "LeadingWildcardDotSkipsDomainItself", rules: "*.example.com", host: "example.com", port: "443", match: false},
+		{name: "CaseInsensitive", rules: "Example.COM", host: "API.example.com.", port: "443", match: true},
+		{name: "DomainWithMatchingPort", rules: "example.com:8080", host: "example.com", port: "8080", match: true},
+		{name: "DomainWithOtherPort", rules: "example.com:8080", host: "example.com", port: "443", match: false},
+		{name: "IPAddress", rules: "10.0.0.1", host: "10.0.0.1", port: "80", match: true},
+		{name: "OtherIPAddress", rules: "10.0.0.1", host: "10.0.0.2", port: "80", match: false},
+		{name: "IPWithPort", rules: "10.0.0.1:8080", host: "10.0.0.1", port: "80", match: false},
+		{name: "IPv6Address", rules: "[::1]:443", host: "[::1]", port: "443", match: true},
+		{name: "CIDR", rules: "192.168.0.0/16", host: "192.168.10.20", port: "80", match: true},
+		{name: "CIDRMiss", rules: "192.168.0.0/16", host: "10.0.0.1", port: "80", match: false},
+		{name: "CIDRDoesNotResolveHostnames", rules: "127.0.0.0/8", host: "localhost", port: "80", match: false},
+		{name: "MultipleRules", rules: "foo.com, 10.0.0.0/8 bar.org", host: "x.bar.org", port: "80", match: true},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, ParseBypassRules(tt.rules).Match(tt.host, tt.port))
+		})
+	}
+
+	loopbackCases := []struct {
+		name  string
+		host  string
+		match bool
+	}{
+		{name: "Localhost", host: "localhost", match: true},
+		{name: "LocalhostUpperCase", host: "LOCALHOST.", match: true},
+		{name: "LoopbackIPv4", host: "127.0.0.1", match: true},
+		{name: "LoopbackIPv4Range", host: "127.1.2.3", match: true},
+		{name: "LoopbackIPv6", host: "[::1]", match: true},
+		{name: "LocalhostSubdomain", host: "app.localhost", match: false},
+		{name: "OtherIP", host: "10.0.0.1", match: false},
+		{name: "OtherHost", host: "example.com", match: false},
+	}
+
+	for _, tt := range loopbackCases {
+		t.Run("Loopback"+tt.name, func(t *testing.T) {
+			rules := ParseBypassRules("")
+			assert.False(t, rules.Match(tt.host, "80"))
+
+			rules.loopback = true
+			assert.Equal(t, tt.match, rules.Match(tt.host, "80"))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/valyala/fasthttp"
+)
+
+// tunneler is implemented by proxies that can establish a tunnel over an already
+// open connection to themselves, which lets ChainProxy use them past the first hop.
+type tunneler interface {
+	proxyAddr() string
+	tunnel(conn net.Conn, addr string) (net.Conn, error)
+}
+
+// ChainError reports the hop of a ChainProxy that failed.
+// Hop is the index of the proxy in the chain and Addr the address it was asked
+// to reach: the next hop, or the target for the last one.
+type ChainError struct {
+	Hop   int
+	Proxy Proxy
+	Addr  string
+	Err   error
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("proxy chain: hop %d (%T) failed to reach %s: %v", e.Hop, e.Proxy, e.Addr, e.Err)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+// ChainProxy is a Proxy that reaches the target through several proxies in turn,
+// for example SOCKS5 then HTTP CONNECT: the first hop is dialed as usual and every
+// following hop is reached through the tunnel opened by the previous one.
+//
+// Each hop applies its own timeout to its handshake, so a hung hop fails on its
+// own deadline. The first hop may be any Proxy; the others must be HTTPProxy,
+// SOCKS4Proxy, SOCKS5Proxy or GatewayProxy.
+type ChainProxy struct {
+	hops []Proxy
+}
+
+// NewChainProxy creates a ChainProxy going through hops in order.
+func NewChainProxy(hops ...Proxy) (*ChainProxy, error) {
+	if len(hops) == 0 {
+		return nil, fmt.Errorf("proxy chain has no hops")
+	}
+
+	for i, hop := range hops[1:] {
+		if _, ok := hop.(tunneler); !ok {
+			return nil, fmt.Errorf("proxy chain: hop %d (%T) cannot be reached through another proxy", i+1, hop)
+		}
+	}
+
+	return &ChainProxy{hops: hops}, nil
+}
+
+// Hops returns the proxies of the chain in order.
+func (c *ChainProxy) Hops() []Proxy {
+	return c.hops
+}
+
+func (c *ChainProxy) Dial() fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		return c.DialContext(context.Background(), "tcp", addr)
+	}
+}
+
+// DialContext connects to addr through every hop in turn. When ctx ends, the
+// connection to the first hop is closed, which aborts whichever hop is in progress.
+func (c *ChainProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	next := func(hop int) string {
+		if hop+1 < len(c.hops) {
+			return c.hops[hop+1].(tunneler).proxyAddr()
+		}
+
+		return addr
+	}
+
+	conn, err := AsContextDialer(c.hops[0]).DialContext(ctx, "tcp", next(0))
+	if err != nil {
+		return nil, &ChainError{Hop: 0, Proxy: c.hops[0], Addr: next(0), Err: err}
+	}
+
+	return handshakeContext(ctx, conn, func(conn net.Conn) (net.Conn, error) {
+		for hop := 1; hop < len(c.hops); hop++ {
+			tunnel, err := c.hops[hop].(tunneler).tunnel(conn, next(hop))
+			if err != nil {
+				return nil, &ChainError{Hop: hop, Proxy: c.hops[hop], Addr: next(hop), Err: err}
+			}
+
+			conn = tunnel
+		}
+
+		return conn, nil
+	})
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewChainProxy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("NoHops", func(t *testing.T) {
+		proxy, err := NewChainProxy()
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("HopCannotBeTunneled", func(t *testing.T) {
+		direct, _ := NewDirectProxy("", 0)
+		httpProxy, _ := NewHTTPProxy("http://127.0.0.1:3128", 0)
+
+		proxy, err := NewChainProxy(httpProxy, direct)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+		assert.Contains(t, err.Error(), "hop 1")
+	})
+
+	t.Run("AnyFirstHop", func(t *testing.T) {
+		direct, _ := NewDirectProxy("", 0)
+		socks, _ := NewSOCKS5Proxy("socks5://127.0.0.1:1080", 0)
+		gateway, _ := NewGatewayProxy(GatewayConfig{URL: "https://gate.example", UsernameTemplate: "user-{session}"})
+
+		proxy, err := NewChainProxy(direct, socks, gateway)
+
+		assert.NoError(t, err)
+		assert.Len(t, proxy.Hops(), 3)
+		assert.Equal(t, "gate.example:443", gateway.proxyAddr())
+	})
+}
+
+func TestChainProxyDial(t *testing.T) {
+	t.Parallel()
+
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("through the chain"))
+	}))
+	defer targetServer.Close()
+
+	target := targetServer.Listener.Addr().String()
+
+	socksListener := startSOCKS5Server(t)
+	defer socksListener.Close()
+
+	httpServer, tunnels := newConnectProxyServer(t)
+	defer httpServer.Close()
+
+	socksProxy, err := NewSOCKS5Proxy(fmt.Sprintf("socks5://%s", socksListener.Addr()), time.Second)
+	assert.NoError(t, err)
+
+	httpProxy, err := NewHTTPProxy(httpServer.URL, time.Second)
+	assert.NoError(t, err)
+
+	t.Run("SOCKS5ThenHTTPConnect", func(t *testing.T) {
+		chain, err := NewChainProxy(socksProxy, httpProxy)
+		assert.NoError(t, err)
+
+		before := tunnels.Load()
+		client := &fasthttp.Client{ReadTimeout: time.Second, WriteTimeout: time.Second, Dial: chain.Dial()}
+
+		req := fasthttp.AcquireRequest()
+		res := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(res)
+
+		req.SetRequestURI(targetServer.URL)
+
+		assert.NoError(t, client.Do(req, res))
+		assert.Equal(t, "through the chain", string(res.Body()))
+		assert.Equal(t, before+1, tunnels.Load())
+	})
+
+	t.Run("ThreeHops", func(t *testing.T) {
+		chain, err := NewChainProxy(httpProxy, socksProxy, httpProxy)
+		assert.NoError(t, err)
+
+		before := tunnels.Load()
+
+		conn, err := chain.Dial()(target)
+		assert.NoError(t, err)
+		_ = conn.Close()
+
+		assert.Equal(t, before+2, tunnels.Load())
+	})
+
+	t.Run("LastHopFails", func(t *testing.T) {
+		chain, err := NewChainProxy(socksProxy, httpProxy)
+		assert.NoError(t, err)
+
+		conn, err := chain.Dial()("127.0.0.1:1")
+		assert.Nil(t, conn)
+		assert.ErrorIs(t, err, ErrProxyConnectFailed)
+
+		var chainErr *ChainError
+		assert.True(t, errors.As(err, &chainErr))
+		assert.Equal(t, 1, chainErr.Hop)
+		assert.Same(t, httpProxy, chainErr.Proxy)
+		assert.Equal(t, "127.0.0.1:1", chainErr.Addr)
+	})
+
+	t.Run("FirstHopCannotReachSecond", func(t *testing.T) {
+		unreachable, err := NewHTTPProxy("http://127.0.0.1:1", time.Second)
+		assert.NoError(t, err)
+
+		chain, err := NewChainProxy(socksProxy, unreachable)
+		assert.NoError(t, err)
+
+		conn, err := chain.Dial()(target)
+		assert.Nil(t, conn)
+
+		var chainErr *ChainError
+		assert.True(t, errors.As(err, &chainErr))
+		assert.Equal(t, 0, chainErr.Hop)
+		assert.Equal(t, "127.0.0.1:1", chainErr.Addr)
+
+		var socksErr *SOCKS5Error
+		assert.True(t, errors.As(err, &socksErr))
+	})
+
+	t.Run("PerHopTimeout", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				defer conn.Close()
+				time.Sleep(time.Second)
+			}
+		}()
+
+		hung, err := NewHTTPProxy(fmt.Sprintf("http://%s", listener.Addr()), 100*time.Millisecond)
+		assert.NoError(t, err)
+
+		chain, err := NewChainProxy(socksProxy, hung)
+		assert.NoError(t, err)
+
+		start := time.Now()
+		conn, err := chain.Dial()(target)
+		assert.Nil(t, conn)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+		var chainErr *ChainError
+		assert.True(t, errors.As(err, &chainErr))
+		assert.Equal(t, 1, chainErr.Hop)
+
+		var timeoutErr interface{ Timeout() bool }
+		assert.True(t, errors.As(err, &timeoutErr) && timeoutErr.Timeout(), "%v", err)
+	})
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of an Entry's circuit breaker.
+type CircuitState int32
+
+const (
+	// CircuitClosed is the normal state: the proxy receives traffic freely.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen means the proxy is quarantined and skipped by Pick
+	// until its cooldown elapses.
+	CircuitOpen
+
+	// CircuitHalfOpen means the cooldown has elapsed and only a limited
+	// number of trial requests are let through to probe the proxy.
+	CircuitHalfOpen
+)
+
+// String returns a human-readable name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker holds the per-Entry breaker state.
+//
+// The breaker never observes requests directly. Instead it is advanced lazily
+// from Stats whenever the pool evaluates the entry, so callers keep reporting
+// results through Stats exactly as before.
+type circuitBreaker struct {
+	mutex sync.Mutex
+	state CircuitState
+
+	// streak counts how many times the breaker has opened since the last
+	// sustained recovery. It drives cooldown escalation via PoolConfig.CooldownBackoff.
+	streak int64
+
+	// cooldown is the duration of the current (or most recent) open period.
+	cooldown time.Duration
+
+	// closedAt is the time the circuit last closed after a quarantine.
+	// It is passed to the HealthPolicy so windowed rules ignore the old incident.
+	closedAt time.Time
+
+	// halfOpenAt is the time the current half-open period started.
+	halfOpenAt time.Time
+
+	// baseSuccess and baseFail snapshot Stats counters when the breaker
+	// goes half-open, so trial results can be told apart from older ones.
+	baseSuccess int64
+	baseFail    int64
+
+	// issued counts trial requests handed out during the current half-open period.
+	issued int64
+
+	// transitions buffers state changes so callbacks can run outside the lock.
+	transitions []CircuitState
+}
+
+// advance moves the breaker through as many transitions as the current Stats
+// justify and reports whether the entry may be offered to the Selector.
+// The caller must hold b.mutex.
+func (b *circuitBreaker) advance(entry *Entry, cfg *PoolConfig, now time.Time) bool {
+	stats := &entry.stats
+
+	for {
+		switch b.state {
+		case CircuitClosed:
+			if !cfg.HealthPolicy.Healthy(entry, b.closedAt) {
+				b.open(cfg)
+				continue
+			}
+
+			if b.streak > 0 && stats.ConsecutiveSuccesses() >= cfg.CooldownResetSuccesses {
+				b.streak = 0
+				b.cooldown = 0
+			}
+
+			return true
+
+		case CircuitOpen:
+			// Failures recorded while open extend the cooldown, as they always have.
+			if now.Sub(stats.LastFailedTime()) < b.cooldown {
+				return false
+			}
+
+			b.halfOpen(stats, now)
+
+		case CircuitHalfOpen:
+			successes := stats.SuccessCount() - b.baseSuccess
+			failures := stats.Failures() - b.baseFail
+			limit := cfg.HalfOpenMaxRequests
+
+			// Reopen as soon as the required success ratio can no longer be reached.
+			reachable := successes + max(limit-successes-failures, 0)
+			if float64(reachable) < cfg.HalfOpenSuccessRatio*float64(limit) {
+				b.open(cfg)
+				continue
+			}
+
+			if successes+failures >= limit {
+				b.closedAt = now
+				entry.warmingSinceUnix.Store(now.UnixNano())
+				b.setState(CircuitClosed)
+				continue
+			}
+
+			// Trial results were never reported back: start a fresh trial
+			// rather than keeping the proxy locked out forever.
+			if now.Sub(b.halfOpenAt) >= b.cooldown {
+				b.halfOpen(stats, now)
+			}
+
+			return b.issued < limit
+		}
+	}
+}
+
+// acquire hands out a trial permit while half-open.
+// In any other state it always succeeds.
+func (b *circuitBreaker) acquire(limit int64) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state != CircuitHalfOpen {
+		return true
+	}
+
+	if b.issued >= limit {
+		return false
+	}
+
+	b.issued++
+	return true
+}
+
+// open trips the breaker and starts a new quarantine. Without a CooldownBackoff
+// the fixed CooldownWindow is used; otherwise each consecutive quarantine asks
+// the strategy for the next, typically longer, delay.
+func (b *circuitBreaker) open(cfg *PoolConfig) {
+	if cfg.CooldownBackoff == nil {
+		b.cooldown = cfg.CooldownWindow
+	} else {
+		b.cooldown = nextDelay(cfg.CooldownBackoff, b.streak, b.cooldown)
+	}
+
+	b.streak++
+	b.setState(CircuitOpen)
+}
+
+func (b *circuitBreaker) halfOpen(stats *Stats, now time.Time) {
+	b.baseSuccess = stats.SuccessCount()
+	b.baseFail = stats.Failures()
+	b.issued = 0
+	b.halfOpenAt = now
+
+	if b.state != CircuitHalfOpen {
+		b.setState(CircuitHalfOpen)
+	}
+}
+
+func (b *circuitBreaker) setState(state CircuitState) {
+	b.state = state
+	b.transitions = append(b.transitions, state)
+}
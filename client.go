@@ -0,0 +1,434 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ClientConfig holds the tuning parameters for a Client.
+type ClientConfig struct {
+	// Pool provides the proxies requests are sent through.
+	// If nil, requests go through the environment proxy (when enabled) or directly.
+	Pool *Pool
+
+	// Bypass lists targets that are always reached directly instead of through
+	// Pool.Pick, using NO_PROXY syntax (see BypassRules).
+	Bypass string
+
+	// PAC, when set, decides the route of every request that does not match
+	// Bypass, taking precedence over Pool and environment proxies.
+	PAC *PAC
+
+	// FromEnvironment makes the Client honour HTTP_PROXY, HTTPS_PROXY,
+	// ALL_PROXY and NO_PROXY (see EnvironmentProxy). NO_PROXY is merged with
+	// Bypass; the environment proxies are used only when Pool is nil.
+	// As in net/http, localhost and loopback targets are then always reached directly.
+	FromEnvironment bool
+
+	// DialTimeout limits how long establishing a direct or environment proxy
+	// connection may take. Zero means no limit.
+	DialTimeout time.Duration
+
+	// ReadTimeout and WriteTimeout are passed to the underlying fasthttp clients.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// MaxConnsPerHost limits the connections open to each target host through
+	// each proxy. Defaults to fasthttp.DefaultMaxConnsPerHost if zero.
+	MaxConnsPerHost int
+
+	// MaxIdleConnDuration is how long an unused keep-alive connection stays open.
+	// A proxy and target host pair unused for that long is forgotten altogether.
+	// Defaults to fasthttp.DefaultMaxIdleConnDuration if zero.
+	MaxIdleConnDuration time.Duration
+
+	// MaxHostClients bounds the number of proxy and target host pairs the Client
+	// keeps connections for. Once reached, the least recently used pair is dropped
+	// and its idle connections closed. Defaults to 1024 if zero.
+	MaxHostClients int
+
+	// MaxRetries is how many more times a request is sent, each time along a newly
+	// chosen route, after a network error or a retryable response (5xx, 429).
+	// Only requests with an idempotent method are retried. Defaults to 0 (no retries).
+	MaxRetries int
+
+	// RetryBackoff, when set, computes the delay before each retry.
+	// Defaults to nil (retry immediately).
+	RetryBackoff Backoff
+}
+
+const defaultMaxHostClients = 1024
+
+// Client sends fasthttp requests through the proxies of a Pool,
+// recording the outcome of every request in the chosen entry's Stats.
+//
+// For every request the Client decides on a route: targets matching the bypass
+// rules are dialed directly, everything else goes where the PAC script says or,
+// without PAC, through Pool.Pick or, without a Pool, through the environment
+// proxy for the target scheme.
+//
+// Keep-alive connections are pooled per Pool entry (or proxy, for other routes)
+// and target host, so a tunnel is reused only by requests taking the same proxy
+// to the same host. The connections of an entry removed from its Pool are closed
+// within a second, or, for those in use, within a second of their last request
+// completing.
+type Client struct {
+	cfg    ClientConfig
+	bypass []*BypassRules
+	env    *EnvironmentProxy
+	direct *DirectProxy
+	retry  retryer
+
+	hosts      *hostClients[*fastClient]
+	transports *hostClients[*transport]
+}
+
+// NewClient creates a Client from cfg. It fails only if FromEnvironment is set
+// and the environment contains an invalid proxy URL.
+func NewClient(cfg ClientConfig) (*Client, error) {
+	direct, _ := NewDirectProxy("", cfg.DialTimeout)
+
+	if cfg.MaxIdleConnDuration <= 0 {
+		cfg.MaxIdleConnDuration = fasthttp.DefaultMaxIdleConnDuration
+	}
+
+	if cfg.MaxHostClients <= 0 {
+		cfg.MaxHostClients = defaultMaxHostClients
+	}
+
+	client := &Client{cfg: cfg, direct: direct, retry: retryer{maxRetries: cfg.MaxRetries, backoff: cfg.RetryBackoff}}
+	client.hosts = newHostClients(cfg.MaxHostClients, cfg.MaxIdleConnDuration, client.newHostClient)
+	client.transports = newHostClients(cfg.MaxHostClients, cfg.MaxIdleConnDuration, client.newTransport)
+	if cfg.Bypass != "" {
+		client.bypass = append(client.bypass, ParseBypassRules(cfg.Bypass))
+	}
+
+	if cfg.FromEnvironment {
+		env, err := ProxyFromEnvironment(cfg.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		client.env = env
+		client.bypass = append(client.bypass, env.NoProxy)
+	}
+
+	return client, nil
+}
+
+// Do sends req through the route chosen for its URI and fills res.
+//
+// When the request went through a Pool entry, the entry's Stats are updated:
+// network errors and retryable responses (5xx, 429) count as failures,
+// anything else as a success with its latency recorded. Failures are retried
+// as configured by MaxRetries and RetryBackoff.
+func (c *Client) Do(req *fasthttp.Request, res *fasthttp.Response) error {
+	return c.retry.do(context.Background(), isReplayable(req), func() (bool, error) {
+		client, entry, err := c.hostClientFor(req.URI())
+		if err != nil {
+			return false, err
+		}
+
+		client.dials.enter()
+		start := time.Now()
+		err = client.Do(req, res)
+		client.dials.leave()
+
+		if entry != nil {
+			recordResult(entry.Stats(), err, res.StatusCode(), time.Since(start))
+		}
+
+		return isFailure(err, res.StatusCode()), err
+	})
+}
+
+// DoContext is like Do, but gives up as soon as ctx ends.
+//
+// The deadline of ctx, if any, bounds the whole request including the dial and
+// any retries. The dial goes through the proxy's DialContext, so that a CONNECT
+// or SOCKS handshake the proxy never answers is aborted on time. When ctx is
+// cancelled, DoContext returns ctx.Err() at once and the request is left to
+// finish in the background; a cancelled request is not recorded in Stats. Its
+// dial, if any, is aborted too, unless other requests to the same host through
+// the same proxy are still waiting for a connection.
+func (c *Client) DoContext(ctx context.Context, req *fasthttp.Request, res *fasthttp.Response) error {
+	if ctx.Done() == nil {
+		return c.Do(req, res)
+	}
+
+	return c.retry.do(ctx, isReplayable(req), func() (bool, error) {
+		return c.attemptContext(ctx, req, res)
+	})
+}
+
+// attemptContext sends req once for DoContext and reports whether it failed
+// in a way worth retrying.
+func (c *Client) attemptContext(ctx context.Context, req *fasthttp.Request, res *fasthttp.Response) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	client, entry, err := c.hostClientFor(req.URI())
+	if err != nil {
+		return false, err
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+
+	// req and res belong to the caller once DoContext returns, so the request
+	// runs on copies that the background goroutine releases if it is abandoned.
+	ownReq := fasthttp.AcquireRequest()
+	ownRes := fasthttp.AcquireResponse()
+	req.CopyTo(ownReq)
+
+	client.dials.enter()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		if hasDeadline {
+			done <- client.DoDeadline(ownReq, ownRes, deadline)
+		} else {
+			done <- client.Do(ownReq, ownRes)
+		}
+	}()
+
+	select {
+	case err = <-done:
+		client.dials.leave()
+		ownRes.CopyTo(res)
+		fasthttp.ReleaseRequest(ownReq)
+		fasthttp.ReleaseResponse(ownRes)
+	case <-ctx.Done():
+		client.dials.leave()
+		go func() {
+			<-done
+			fasthttp.ReleaseRequest(ownReq)
+			fasthttp.ReleaseResponse(ownRes)
+		}()
+
+		return false, ctx.Err()
+	}
+
+	if entry != nil {
+		recordResult(entry.Stats(), err, res.StatusCode(), time.Since(start))
+	}
+
+	return isFailure(err, res.StatusCode()), err
+}
+
+// isReplayable reports whether req may be sent again after a failure.
+func isReplayable(req *fasthttp.Request) bool {
+	return isIdempotent(string(req.Header.Method())) && !req.IsBodyStream()
+}
+
+// route returns the Proxy a request to rawURL, with the given scheme and host,
+// should go through and, when it was picked from the Pool, the corresponding Entry.
+func (c *Client) route(scheme, hostport, rawURL string) (*Entry, Proxy, error) {
+	host, port := splitTargetHost(hostport, scheme)
+
+	for _, rules := range c.bypass {
+		if rules.Match(host, port) {
+			return nil, c.direct, nil
+		}
+	}
+
+	if c.cfg.PAC != nil {
+		proxy, err := c.cfg.PAC.ProxyFor(rawURL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, proxy, nil
+	}
+
+	if c.cfg.Pool != nil {
+		entry, err := c.cfg.Pool.Pick()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return entry, entry.Proxy(), nil
+	}
+
+	if c.env != nil {
+		if proxy := c.env.ProxyFor(scheme); proxy != nil {
+			return nil, proxy, nil
+		}
+	}
+
+	return nil, c.direct, nil
+}
+
+// CloseIdleConnections closes the idle keep-alive connections through every proxy,
+// of both Do and RoundTrip.
+func (c *Client) CloseIdleConnections() {
+	c.hosts.closeIdle()
+	c.transports.closeIdle()
+}
+
+// hostClientFor routes a request to uri and returns the HostClient to send it
+// with, along with the Pool entry it goes through, if any.
+func (c *Client) hostClientFor(uri *fasthttp.URI) (*fastClient, *Entry, error) {
+	isTLS, err := isTLSScheme(string(uri.Scheme()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(uri.Host()) == 0 {
+		return nil, nil, fasthttp.ErrorInvalidURI
+	}
+
+	entry, proxy, err := c.route(string(uri.Scheme()), string(uri.Host()), uri.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.hosts.get(hostKey{entry: entry, proxy: proxy, host: string(uri.Host()), isTLS: isTLS}), entry, nil
+}
+
+// fastClient is a fasthttp.HostClient dialing through a single proxy.
+type fastClient struct {
+	*fasthttp.HostClient
+	dials dialGroup
+}
+
+func (c *Client) newHostClient(key hostKey) *fastClient {
+	dialer := AsContextDialer(key.proxy)
+	client := &fastClient{}
+
+	client.HostClient = &fasthttp.HostClient{
+		Addr:  fasthttp.AddMissingPort(key.host, key.isTLS),
+		IsTLS: key.isTLS,
+		DialTimeout: func(addr string, timeout time.Duration) (net.Conn, error) {
+			ctx, err := client.dials.context()
+			if err != nil {
+				return nil, err
+			}
+
+			if timeout <= 0 {
+				return dialer.DialContext(ctx, "tcp", addr)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return dialer.DialContext(ctx, "tcp", addr)
+		},
+		MaxConns:            c.cfg.MaxConnsPerHost,
+		MaxIdleConnDuration: c.cfg.MaxIdleConnDuration,
+		ReadTimeout:         c.cfg.ReadTimeout,
+		WriteTimeout:        c.cfg.WriteTimeout,
+	}
+
+	return client
+}
+
+// errNoWaitingRequest is returned by dials started after every request that
+// could use the connection has been abandoned.
+var errNoWaitingRequest = errors.New("no request is waiting for the connection")
+
+// dialGroup ties the dials of a HostClient to the requests waiting on it.
+//
+// fasthttp dials on behalf of the HostClient rather than of a request, and any
+// waiting request may get the new connection. A dial is therefore aborted only
+// once no request waits any more, which is when the last one is abandoned by
+// its context.
+type dialGroup struct {
+	mutex   sync.Mutex
+	waiting int
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// enter registers a request that may need a new connection.
+func (g *dialGroup) enter() {
+	g.mutex.Lock()
+	g.waiting++
+	g.mutex.Unlock()
+}
+
+// leave unregisters a request, completed or abandoned, and aborts the dials
+// in progress if it was the last one waiting.
+func (g *dialGroup) leave() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.waiting--
+	if g.waiting == 0 && g.cancel != nil {
+		g.cancel()
+		g.ctx, g.cancel = nil, nil
+	}
+}
+
+// context returns the context a new dial must use.
+func (g *dialGroup) context() (context.Context, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.waiting == 0 {
+		return nil, errNoWaitingRequest
+	}
+
+	if g.ctx == nil {
+		g.ctx, g.cancel = context.WithCancel(context.Background())
+	}
+
+	return g.ctx, nil
+}
+
+// recordResult classifies the outcome of a request and records it in stats.
+func recordResult(stats *Stats, err error, statusCode int, latency time.Duration) {
+	if isFailure(err, statusCode) {
+		stats.RecordFailed()
+		return
+	}
+
+	stats.RecordSuccess()
+	stats.RecordLatency(latency)
+}
+
+// isTLSScheme reports whether requests with the given scheme use TLS,
+// failing for schemes other than http and https.
+func isTLSScheme(scheme string) (bool, error) {
+	switch strings.ToLower(scheme) {
+	case "https":
+		return true, nil
+	case "http":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported protocol '%s': http and https are supported", scheme)
+	}
+}
+
+// isFailure reports whether a request outcome counts against the proxy it went
+// through: a network error or a retryable response.
+func isFailure(err error, statusCode int) bool {
+	return err != nil || isRetryableStatus(statusCode)
+}
+
+// isRetryableStatus reports whether a response status indicates a problem
+// with the proxy or an overloaded upstream rather than with the request itself.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode >= fasthttp.StatusInternalServerError || statusCode == fasthttp.StatusTooManyRequests
+}
+
+// splitTargetHost splits a URI host into hostname and port,
+// filling in the default port of the scheme when none is given.
+func splitTargetHost(hostport, scheme string) (string, string) {
+	if host, port, err := net.SplitHostPort(hostport); err == nil {
+		return host, port
+	}
+
+	if strings.EqualFold(scheme, "https") {
+		return hostport, "443"
+	}
+
+	return hostport, "80"
+}
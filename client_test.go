@@ -0,0 +1,456 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// newConnectProxyServer starts an HTTP CONNECT proxy that tunnels to any target
+// and counts the tunnels it has established.
+func newConnectProxyServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	tunnels := &atomic.Int64{}
+	return httptest.NewServer(newConnectProxyHandler(tunnels, nil)), tunnels
+}
+
+// newConnectProxyHandler returns a CONNECT proxy handler that counts established
+// tunnels and passes every CONNECT request to inspect, if set, before dialing.
+func newConnectProxyHandler(tunnels *atomic.Int64, inspect func(r *http.Request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if inspect != nil {
+			inspect(r)
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+
+		tunnels.Add(1)
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	})
+}
+
+func doRequest(t *testing.T, client *Client, uri string) *fasthttp.Response {
+	t.Helper()
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(uri)
+
+	res := &fasthttp.Response{}
+	assert.NoError(t, client.Do(req, res))
+
+	return res
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	proxyServer, tunnels := newConnectProxyServer(t)
+	defer proxyServer.Close()
+
+	newPool := func(t *testing.T) *Pool {
+		proxy, err := NewHTTPProxy(proxyServer.URL, time.Second)
+		assert.NoError(t, err)
+
+		return NewPool([]Proxy{proxy}, PoolConfig{})
+	}
+
+	t.Run("RequestThroughPoolRecordsStats", func(t *testing.T) {
+		pool := newPool(t)
+
+		client, err := NewClient(ClientConfig{Pool: pool, ReadTimeout: time.Second, WriteTimeout: time.Second})
+		assert.NoError(t, err)
+
+		before := tunnels.Load()
+
+		res := doRequest(t, client, targetServer.URL)
+		assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+		assert.Equal(t, before+1, tunnels.Load())
+
+		res = doRequest(t, client, targetServer.URL+"/fail")
+		assert.Equal(t, fasthttp.StatusServiceUnavailable, res.StatusCode())
+
+		stats := pool.Entries()[0].Stats()
+		assert.Equal(t, int64(1), stats.SuccessCount())
+		assert.Equal(t, int64(1), stats.Failures())
+		assert.Greater(t, stats.AvgLatencyMs(), 0.0)
+	})
+
+	t.Run("TunnelsReusedPerProxy", func(t *testing.T) {
+		first, err := NewHTTPProxy(proxyServer.URL, time.Second)
+		assert.NoError(t, err)
+
+		second, err := NewHTTPProxy(proxyServer.URL, time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{first, second}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxConnsPerHost: 4})
+		assert.NoError(t, err)
+
+		before := tunnels.Load()
+
+		for range 6 {
+			res := doRequest(t, client, targetServer.URL)
+			assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+		}
+
+		assert.Equal(t, before+2, tunnels.Load())
+		assert.Equal(t, int64(3), pool.Entries()[0].Stats().SuccessCount())
+		assert.Equal(t, int64(3), pool.Entries()[1].Stats().SuccessCount())
+
+		hostClient := client.hosts.get(hostKey{entry: pool.Entries()[0], proxy: first, host: targetServer.Listener.Addr().String()})
+		assert.Equal(t, 4, hostClient.MaxConns)
+		assert.Equal(t, 1, hostClient.ConnsCount())
+	})
+
+	t.Run("UnsupportedScheme", func(t *testing.T) {
+		pool := newPool(t)
+
+		client, err := NewClient(ClientConfig{Pool: pool})
+		assert.NoError(t, err)
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		req.SetRequestURI("ftp://127.0.0.1/file")
+		assert.ErrorContains(t, client.Do(req, &fasthttp.Response{}), "unsupported protocol")
+		assert.Equal(t, int64(0), pool.Entries()[0].Stats().Failures())
+	})
+
+	t.Run("BypassRoutesDirect", func(t *testing.T) {
+		pool := newPool(t)
+
+		client, err := NewClient(ClientConfig{Pool: pool, Bypass: "127.0.0.0/8"})
+		assert.NoError(t, err)
+
+		before := tunnels.Load()
+
+		res := doRequest(t, client, targetServer.URL)
+		assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+		assert.Equal(t, before, tunnels.Load())
+		assert.Equal(t, int64(0), pool.Entries()[0].Stats().SuccessCount())
+	})
+
+	t.Run("EmptyPool", func(t *testing.T) {
+		client, err := NewClient(ClientConfig{Pool: NewPool(nil, PoolConfig{})})
+		assert.NoError(t, err)
+
+		req := fasthttp.AcquireRequest()
+		res := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(res)
+
+		req.SetRequestURI(targetServer.URL)
+		assert.ErrorIs(t, client.Do(req, res), ErrProxyPoolEmpty)
+	})
+
+	t.Run("NetworkErrorRecordedAsFailure", func(t *testing.T) {
+		proxy, err := NewHTTPProxy("http://127.0.0.1:1", time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{proxy}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool})
+		assert.NoError(t, err)
+
+		req := fasthttp.AcquireRequest()
+		res := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(res)
+
+		req.SetRequestURI(targetServer.URL)
+		assert.Error(t, client.Do(req, res))
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().Failures())
+	})
+}
+
+func TestClientFromEnvironment(t *testing.T) {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	// Loopback targets are never proxied, so the proxy maps a public name to the target.
+	tunnels := &atomic.Int64{}
+	proxyServer := httptest.NewServer(newConnectProxyHandler(tunnels, func(r *http.Request) {
+		r.Host = targetServer.Listener.Addr().String()
+	}))
+	defer proxyServer.Close()
+
+	t.Setenv("HTTP_PROXY", proxyServer.URL)
+	t.Setenv("NO_PROXY", "")
+
+	client, err := NewClient(ClientConfig{FromEnvironment: true})
+	assert.NoError(t, err)
+
+	doRequest(t, client, "http://target.example/")
+	assert.Equal(t, int64(1), tunnels.Load())
+
+	doRequest(t, client, targetServer.URL)
+	assert.Equal(t, int64(1), tunnels.Load())
+
+	t.Setenv("NO_PROXY", "target.example")
+
+	client, err = NewClient(ClientConfig{FromEnvironment: true})
+	assert.NoError(t, err)
+
+	_, proxy, err := client.route("http", "target.example", "http://target.example/")
+	assert.NoError(t, err)
+	assert.Same(t, client.direct, proxy)
+
+	t.Setenv("HTTP_PROXY", "ftp://127.0.0.1")
+
+	client, err = NewClient(ClientConfig{FromEnvironment: true})
+	assert.Error(t, err)
+	assert.Nil(t, client)
+}
+
+func TestSplitTargetHost(t *testing.T) {
+	t.Parallel()
+
+	host, port := splitTargetHost("example.com:8080", "http")
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, "8080", port)
+
+	host, port = splitTargetHost("example.com", "https")
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, "443", port)
+
+	host, port = splitTargetHost("example.com", "http")
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, "80", port)
+}
+
+func TestClientDoContext(t *testing.T) {
+	t.Parallel()
+
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer targetServer.Close()
+
+	listener, accepted := newHungProxyListener(t)
+	defer listener.Close()
+
+	hung, err := NewHTTPProxy("http://"+listener.Addr().String(), 0)
+	assert.NoError(t, err)
+
+	newRequest := func() *fasthttp.Request {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(targetServer.URL)
+		return req
+	}
+
+	t.Run("DeadlineAbortsHungHandshake", func(t *testing.T) {
+		pool := NewPool([]Proxy{hung}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		req := newRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		start := time.Now()
+		err = client.DoContext(ctx, req, &fasthttp.Response{})
+
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assertClosedByPeer(t, <-accepted)
+	})
+
+	t.Run("CancellationAbortsHungHandshake", func(t *testing.T) {
+		pool := NewPool([]Proxy{hung}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		req := newRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		start := time.Now()
+		err = client.DoContext(ctx, req, &fasthttp.Response{})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, int64(0), pool.Entries()[0].Stats().Failures())
+		assertClosedByPeer(t, <-accepted)
+	})
+
+	t.Run("CompletesWithinContext", func(t *testing.T) {
+		proxyServer, tunnels := newConnectProxyServer(t)
+		defer proxyServer.Close()
+
+		proxy, err := NewHTTPProxy(proxyServer.URL, 0)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{proxy}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		req := newRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		res := &fasthttp.Response{}
+		assert.NoError(t, client.DoContext(ctx, req, res))
+		assert.Equal(t, "ok", string(res.Body()))
+		assert.Equal(t, int64(1), tunnels.Load())
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().SuccessCount())
+	})
+
+	t.Run("ContextAlreadyDone", func(t *testing.T) {
+		client, err := NewClient(ClientConfig{})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req := newRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		assert.ErrorIs(t, client.DoContext(ctx, req, &fasthttp.Response{}), context.Canceled)
+	})
+}
+
+func TestClientRetries(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int64
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	dead, err := NewHTTPProxy("http://127.0.0.1:1", time.Second)
+	assert.NoError(t, err)
+
+	direct, err := NewDirectProxy("", time.Second)
+	assert.NoError(t, err)
+
+	newRequest := func(method, uri string) *fasthttp.Request {
+		req := fasthttp.AcquireRequest()
+		req.Header.SetMethod(method)
+		req.SetRequestURI(uri)
+		return req
+	}
+
+	t.Run("NetworkErrorRetriedThroughNextProxy", func(t *testing.T) {
+		// RoundRobinSelector starts at the second entry, so the dead proxy goes first.
+		pool := NewPool([]Proxy{direct, dead}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 1, RetryBackoff: NewEqualJitter(time.Millisecond, time.Millisecond)})
+		assert.NoError(t, err)
+
+		req := newRequest(fasthttp.MethodGet, targetServer.URL)
+		defer fasthttp.ReleaseRequest(req)
+
+		res := &fasthttp.Response{}
+		assert.NoError(t, client.Do(req, res))
+		assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+
+		entries := pool.Entries()
+		assert.Equal(t, int64(1), entries[1].Stats().Failures())
+		assert.Equal(t, int64(1), entries[0].Stats().SuccessCount())
+	})
+
+	t.Run("RetryableStatusRetriedUntilExhausted", func(t *testing.T) {
+		pool := NewPool([]Proxy{direct}, PoolConfig{MaxFails: 10})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 2})
+		assert.NoError(t, err)
+
+		req := newRequest(fasthttp.MethodGet, targetServer.URL+"/fail")
+		defer fasthttp.ReleaseRequest(req)
+
+		before := hits.Load()
+
+		res := &fasthttp.Response{}
+		assert.NoError(t, client.DoContext(context.Background(), req, res))
+		assert.Equal(t, fasthttp.StatusServiceUnavailable, res.StatusCode())
+		assert.Equal(t, before+3, hits.Load())
+		assert.Equal(t, int64(3), pool.Entries()[0].Stats().Failures())
+	})
+
+	t.Run("NonIdempotentRequestNotRetried", func(t *testing.T) {
+		pool := NewPool([]Proxy{dead}, PoolConfig{MaxFails: 10})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 3})
+		assert.NoError(t, err)
+
+		req := newRequest(fasthttp.MethodPost, targetServer.URL)
+		defer fasthttp.ReleaseRequest(req)
+
+		assert.Error(t, client.Do(req, &fasthttp.Response{}))
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().Failures())
+	})
+
+	t.Run("BackoffInterruptedByContext", func(t *testing.T) {
+		pool := NewPool([]Proxy{dead}, PoolConfig{MaxFails: 10})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 1, RetryBackoff: NewEqualJitter(time.Hour, time.Hour)})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		req := newRequest(fasthttp.MethodGet, targetServer.URL)
+		defer fasthttp.ReleaseRequest(req)
+
+		start := time.Now()
+		assert.ErrorIs(t, client.DoContext(ctx, req, &fasthttp.Response{}), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().Failures())
+	})
+}
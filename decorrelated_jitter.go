@@ -64,3 +64,8 @@ func (d *DecorrelatedJitter) Next(previousDelay int64) time.Duration {
 
 	return d.baseDelay + time.Duration(rand.Int64N(diff+1))
 }
+
+// NextAfter implements StatefulBackoff: it is Next with previous in nanoseconds.
+func (d *DecorrelatedJitter) NextAfter(previous time.Duration) time.Duration {
+	return d.Next(previous.Nanoseconds())
+}
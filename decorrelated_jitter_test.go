@@ -97,4 +97,24 @@ func TestDecorrelatedJitter(t *testing.T) {
 			assert.True(t, delay <= maxDelay)
 		}
 	})
+	t.Run("NextDelayUsesPreviousDelay", func(t *testing.T) {
+		baseDelay := 1 * time.Second
+		maxDelay := 100 * time.Second
+
+		decorrelatedJitter := NewDecorrelatedJitter(baseDelay, maxDelay)
+		assert.Implements(t, (*StatefulBackoff)(nil), decorrelatedJitter)
+
+		// With the attempt number as argument every delay would be baseDelay.
+		longest := time.Duration(0)
+		for i := 0; i < 15; i++ {
+			delay := nextDelay(decorrelatedJitter, 1, 10*time.Second)
+			assert.True(t, delay >= baseDelay)
+			assert.True(t, delay < 30*time.Second)
+			longest = max(longest, delay)
+		}
+
+		assert.Greater(t, longest, baseDelay)
+
+		assert.Equal(t, baseDelay, nextDelay(decorrelatedJitter, 5, 0))
+	})
 }
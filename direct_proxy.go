@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DirectProxy is a Proxy that does not proxy at all: it dials the target directly.
+//
+// It lets direct egress take part in a Pool like any other proxy, with its own
+// Stats and health tracking. Bound to a specific local address, several DirectProxy
+// instances let a multi-IP host rotate its own source addresses through the same
+// Pool and Selector machinery.
+type DirectProxy struct {
+	dialer  *net.Dialer
+	network string
+}
+
+// NewDirectProxy creates a DirectProxy that dials from localIP.
+// An empty localIP lets the operating system choose the source address.
+// A non-zero timeout limits how long establishing a connection may take.
+//
+// When localIP is set, only targets of the same address family can be reached,
+// so the dialer is restricted to "tcp4" or "tcp6" accordingly.
+func NewDirectProxy(localIP string, timeout time.Duration) (*DirectProxy, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if localIP == "" {
+		return &DirectProxy{dialer: dialer, network: "tcp"}, nil
+	}
+
+	ip := net.ParseIP(localIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid local address: expected an IP, got '%s'", localIP)
+	}
+
+	dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	return &DirectProxy{dialer: dialer, network: networkFor(ip)}, nil
+}
+
+// NewInterfaceDirectProxy creates a DirectProxy that dials from the first address
+// of the named network interface, preferring IPv4 over IPv6.
+func NewInterfaceDirectProxy(name string, timeout time.Duration) (*DirectProxy, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var chosen net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		if ipNet.IP.To4() != nil {
+			chosen = ipNet.IP
+			break
+		}
+
+		if chosen == nil {
+			chosen = ipNet.IP
+		}
+	}
+
+	if chosen == nil {
+		return nil, fmt.Errorf("interface '%s' has no IP address", name)
+	}
+
+	return NewDirectProxy(chosen.String(), timeout)
+}
+
+// NewDirectProxyWithDialer creates a DirectProxy that uses the given dialer as is,
+// for full control over local address, keep-alive and socket options.
+// A nil dialer is replaced with a zero net.Dialer.
+func NewDirectProxyWithDialer(dialer *net.Dialer) *DirectProxy {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	network := "tcp"
+	if addr, ok := dialer.LocalAddr.(*net.TCPAddr); ok && addr.IP != nil {
+		network = networkFor(addr.IP)
+	}
+
+	return &DirectProxy{dialer: dialer, network: network}
+}
+
+func (d *DirectProxy) Dial() fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		return d.dialer.Dial(d.network, addr)
+	}
+}
+
+// DialContext dials addr directly, aborting as soon as ctx ends.
+// A "tcp" network is narrowed to the address family of the local address, if one is bound.
+func (d *DirectProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	if network == "tcp" {
+		network = d.network
+	}
+
+	return d.dialer.DialContext(ctx, network, addr)
+}
+
+func networkFor(ip net.IP) string {
+	if ip.To4() != nil {
+		return "tcp4"
+	}
+
+	return "tcp6"
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDirectProxy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ValidWithoutLocalAddress", func(t *testing.T) {
+		proxy, err := NewDirectProxy("", time.Second)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, proxy)
+		assert.Nil(t, proxy.dialer.LocalAddr)
+		assert.Equal(t, "tcp", proxy.network)
+		assert.Equal(t, time.Second, proxy.dialer.Timeout)
+	})
+
+	t.Run("ValidWithLocalAddress", func(t *testing.T) {
+		proxy, err := NewDirectProxy("127.0.0.1", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "tcp4", proxy.network)
+
+		proxy, err = NewDirectProxy("::1", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "tcp6", proxy.network)
+	})
+
+	t.Run("InvalidLocalAddress", func(t *testing.T) {
+		proxy, err := NewDirectProxy("not-an-ip", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+		assert.Contains(t, err.Error(), "invalid local address")
+	})
+
+	t.Run("InvalidInterface", func(t *testing.T) {
+		proxy, err := NewInterfaceDirectProxy("does-not-exist0", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("LoopbackInterface", func(t *testing.T) {
+		interfaces, err := net.Interfaces()
+		assert.NoError(t, err)
+
+		for _, iface := range interfaces {
+			if iface.Flags&net.FlagLoopback == 0 {
+				continue
+			}
+
+			proxy, err := NewInterfaceDirectProxy(iface.Name, 0)
+			assert.NoError(t, err)
+
+			addr := proxy.dialer.LocalAddr.(*net.TCPAddr)
+			assert.True(t, addr.IP.IsLoopback())
+			return
+		}
+
+		t.Skip("no loopback interface")
+	})
+
+	t.Run("WithDialer", func(t *testing.T) {
+		proxy := NewDirectProxyWithDialer(nil)
+		assert.NotNil(t, proxy.dialer)
+		assert.Equal(t, "tcp", proxy.network)
+
+		dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}}
+		proxy = NewDirectProxyWithDialer(dialer)
+		assert.Equal(t, dialer, proxy.dialer)
+		assert.Equal(t, "tcp4", proxy.network)
+	})
+}
+
+func TestDirectProxyDial(t *testing.T) {
+	t.Parallel()
+
+	remoteAddr := make(chan string, 1)
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr <- r.RemoteAddr
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer targetServer.Close()
+
+	// Linux routes the whole 127.0.0.0/8 to loopback, so a second source address is available.
+	probe, err := net.ListenTCP("tcp4", &net.TCPAddr{IP: net.ParseIP("127.0.0.2")})
+	if err != nil {
+		t.Skip("127.0.0.2 is not available on this host")
+	}
+	_ = probe.Close()
+
+	proxy, err := NewDirectProxy("127.0.0.2", time.Second)
+	assert.NoError(t, err)
+
+	client := &fasthttp.Client{ReadTimeout: time.Second, WriteTimeout: time.Second, Dial: proxy.Dial()}
+
+	req := fasthttp.AcquireRequest()
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(targetServer.URL)
+	err = client.Do(req, res)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+	assert.Equal(t, "direct", string(res.Body()))
+
+	host, _, err := net.SplitHostPort(<-remoteAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.2", host)
+}
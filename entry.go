@@ -1,6 +1,9 @@
 package client
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
 
 // Entry is the internal unit of the pool.
 // It binds a Proxy to its Stats and exposes health-check logic.
@@ -8,12 +11,35 @@ import "time"
 // Entry is the only place in the package that knows about both Proxy and Stats,
 // keeping the two concerns separate while providing a convenient handle for the pool.
 type Entry struct {
-	proxy Proxy
-	stats Stats
+	proxy   Proxy
+	stats   Stats
+	breaker circuitBreaker
+
+	// staticWeight is the operator-assigned share of traffic used by
+	// SmoothWeightedSelector. It is independent of Stats.Weight.
+	staticWeight atomic.Int64
+
+	// slowStart is the ramp-up window copied from PoolConfig.SlowStart.
+	// It is set before the entry is published to the pool and never changes.
+	slowStart time.Duration
+
+	// warmingSinceUnix is the UnixNano timestamp at which the entry was added
+	// to a running pool or recovered from quarantine; zero if never.
+	warmingSinceUnix atomic.Int64
+
+	// removed is set once the entry has been removed from its pool.
+	removed atomic.Bool
 }
 
+// slowStartMinFactor is the share of its full weight an entry gets
+// at the very beginning of its slow-start window.
+const slowStartMinFactor = 0.1
+
 func newEntry(proxy Proxy) *Entry {
-	return &Entry{proxy: proxy, stats: Stats{}}
+	entry := &Entry{proxy: proxy, stats: Stats{}}
+	entry.staticWeight.Store(1)
+
+	return entry
 }
 
 func (e *Entry) Proxy() Proxy {
@@ -24,12 +50,64 @@ func (e *Entry) Stats() *Stats {
 	return &e.stats
 }
 
-// HealthCheck reports whether this proxy is eligible to receive requests.
+// Removed reports whether the entry has been removed from its pool.
+// A Client closes the connections it keeps through a removed entry.
+func (e *Entry) Removed() bool {
+	return e.removed.Load()
+}
+
+// StaticWeight returns the operator-assigned weight of this proxy. Defaults to 1.
+func (e *Entry) StaticWeight() int64 {
+	return e.staticWeight.Load()
+}
+
+// SetStaticWeight assigns the share of traffic this proxy should receive relative
+// to the others, for example in proportion to what each proxy costs.
+// A weight of 0 drains the proxy; negative weights are treated as 0.
+func (e *Entry) SetStaticWeight(weight int64) {
+	e.staticWeight.Store(max(weight, 0))
+}
+
+// SlowStartFactor returns the fraction (0.1–1.0) of its full weight this proxy
+// should currently receive. Right after the proxy was added to a running pool or
+// recovered from quarantine the factor is 0.1; it then grows linearly to 1.0
+// over PoolConfig.SlowStart. Weight-aware selectors multiply their score by it.
+func (e *Entry) SlowStartFactor() float64 {
+	since := e.warmingSinceUnix.Load()
+	if e.slowStart <= 0 || since == 0 {
+		return 1
+	}
+
+	elapsed := time.Since(time.Unix(0, since))
+	if elapsed >= e.slowStart {
+		return 1
+	}
+
+	return slowStartMinFactor + (1-slowStartMinFactor)*float64(max(elapsed, 0))/float64(e.slowStart)
+}
+
+// State returns the current circuit breaker state of this proxy.
+func (e *Entry) State() CircuitState {
+	return e.stats.CircuitState()
+}
+
+// QuarantineStreak returns how many times in a row this proxy has been
+// quarantined since its last sustained recovery.
+func (e *Entry) QuarantineStreak() int64 {
+	e.breaker.mutex.Lock()
+	defer e.breaker.mutex.Unlock()
+
+	return e.breaker.streak
+}
+
+// HealthCheck reports whether the proxy has fewer than maxFails consecutive
+// failures, or at least cooldown has passed since the last one.
 //
-// A proxy is considered unhealthy when it has accumulated maxConsecutiveFails
-// failures in a row and the cooldown window has not yet elapsed since the
-// last failure. Once the cooldown expires the proxy is given a second chance
-// automatically — no manual reset is required.
+// It only evaluates Stats and does not decide whether the Pool offers the entry:
+// the circuit breaker does, with the HealthPolicy, cooldown and half-open trial
+// limits of the PoolConfig.
+//
+// Deprecated: use State to check whether the Pool currently offers the entry.
 func (e *Entry) HealthCheck(maxFails int64, cooldown time.Duration) bool {
 	if e.stats.ConsecutiveFails() < maxFails {
 		return true
@@ -37,3 +115,27 @@ func (e *Entry) HealthCheck(maxFails int64, cooldown time.Duration) bool {
 
 	return time.Since(e.stats.LastFailedTime()) >= cooldown
 }
+
+// available advances the circuit breaker and reports whether this entry
+// may be offered to the Selector under cfg.
+//
+// Transition callbacks configured in cfg are invoked after the breaker
+// lock is released, in the order the transitions happened.
+func (e *Entry) available(cfg *PoolConfig) bool {
+	e.breaker.mutex.Lock()
+	from := e.breaker.state
+	allowed := e.breaker.advance(e, cfg, time.Now())
+	transitions := e.breaker.transitions
+	e.breaker.transitions = nil
+	e.stats.circuitState.Store(int32(e.breaker.state))
+	e.breaker.mutex.Unlock()
+
+	if cfg.OnStateChange != nil {
+		for _, to := range transitions {
+			cfg.OnStateChange(e, from, to)
+			from = to
+		}
+	}
+
+	return allowed
+}
@@ -7,6 +7,14 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+type linearBackoff struct {
+	step time.Duration
+}
+
+func (l *linearBackoff) Next(attempt int64) time.Duration {
+	return time.Duration(attempt+1) * l.step
+}
+
 func TestProxyEntry(t *testing.T) {
 	t.Parallel()
 
@@ -54,4 +62,167 @@ func TestProxyEntry(t *testing.T) {
 		isHealthy := entry.HealthCheck(1, 10*time.Millisecond)
 		assert.True(t, isHealthy)
 	})
+	t.Run("BreakerOpensAfterMaxFails", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+		cfg := &PoolConfig{HealthPolicy: NewConsecutiveFailsPolicy(2), CooldownWindow: time.Minute, HalfOpenMaxRequests: 1, HalfOpenSuccessRatio: 1}
+
+		assert.True(t, entry.available(cfg))
+		assert.Equal(t, CircuitClosed, entry.State())
+
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordFailed()
+
+		assert.False(t, entry.available(cfg))
+		assert.Equal(t, CircuitOpen, entry.State())
+		assert.Equal(t, CircuitOpen, entry.Stats().CircuitState())
+		assert.Equal(t, int64(1), entry.QuarantineStreak())
+	})
+
+	t.Run("BreakerHalfOpenLimitsTrialRequests", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+		cfg := &PoolConfig{HealthPolicy: NewConsecutiveFailsPolicy(1), CooldownWindow: 10 * time.Millisecond, HalfOpenMaxRequests: 2, HalfOpenSuccessRatio: 1}
+
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+
+		time.Sleep(15 * time.Millisecond)
+
+		assert.True(t, entry.available(cfg))
+		assert.Equal(t, CircuitHalfOpen, entry.State())
+
+		assert.True(t, entry.breaker.acquire(cfg.HalfOpenMaxRequests))
+		assert.True(t, entry.breaker.acquire(cfg.HalfOpenMaxRequests))
+		assert.False(t, entry.breaker.acquire(cfg.HalfOpenMaxRequests))
+		assert.False(t, entry.available(cfg))
+
+		entry.Stats().RecordSuccess()
+		assert.Equal(t, CircuitHalfOpen, entry.State())
+
+		assert.Equal(t, int64(0), entry.warmingSinceUnix.Load())
+
+		entry.Stats().RecordSuccess()
+		assert.True(t, entry.available(cfg))
+		assert.Equal(t, CircuitClosed, entry.State())
+		assert.NotEqual(t, int64(0), entry.warmingSinceUnix.Load(), "recovered entry should enter slow start")
+	})
+
+	t.Run("BreakerReopensOnTrialFailure", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+		cfg := &PoolConfig{HealthPolicy: NewConsecutiveFailsPolicy(3), CooldownWindow: 10 * time.Millisecond, HalfOpenMaxRequests: 3, HalfOpenSuccessRatio: 0.6}
+
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+
+		time.Sleep(15 * time.Millisecond)
+		assert.True(t, entry.available(cfg))
+
+		// One failure out of three trials still allows a 0.6 success ratio.
+		entry.Stats().RecordFailed()
+		assert.True(t, entry.available(cfg))
+		assert.Equal(t, CircuitHalfOpen, entry.State())
+
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+		assert.Equal(t, CircuitOpen, entry.State())
+		assert.Equal(t, int64(2), entry.QuarantineStreak())
+	})
+
+	t.Run("BreakerTransitionCallbacks", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+
+		var transitions []string
+		cfg := &PoolConfig{
+			HealthPolicy:         NewConsecutiveFailsPolicy(1),
+			CooldownWindow:       time.Nanosecond,
+			HalfOpenMaxRequests:  1,
+			HalfOpenSuccessRatio: 1,
+			OnStateChange: func(e *Entry, from, to CircuitState) {
+				assert.Equal(t, entry, e)
+				transitions = append(transitions, from.String()+"->"+to.String())
+			},
+		}
+
+		entry.Stats().RecordFailed()
+		time.Sleep(time.Millisecond)
+		assert.True(t, entry.available(cfg))
+
+		entry.Stats().RecordSuccess()
+		assert.True(t, entry.available(cfg))
+
+		assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, transitions)
+	})
+
+	t.Run("CooldownWithoutBackoffIsFixed", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+		cfg := &PoolConfig{HealthPolicy: NewConsecutiveFailsPolicy(1), CooldownWindow: 10 * time.Millisecond, HalfOpenMaxRequests: 1, HalfOpenSuccessRatio: 1}
+
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+		assert.Equal(t, 10*time.Millisecond, entry.breaker.cooldown)
+
+		time.Sleep(15 * time.Millisecond)
+		assert.True(t, entry.available(cfg))
+
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+		assert.Equal(t, 10*time.Millisecond, entry.breaker.cooldown)
+		assert.Equal(t, int64(2), entry.QuarantineStreak())
+	})
+
+	t.Run("CooldownEscalatesOnRepeatedQuarantine", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+		cfg := &PoolConfig{
+			HealthPolicy:           NewConsecutiveFailsPolicy(1),
+			CooldownBackoff:        &linearBackoff{step: 10 * time.Millisecond},
+			CooldownResetSuccesses: 2,
+			HalfOpenMaxRequests:    1,
+			HalfOpenSuccessRatio:   1,
+		}
+
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+		assert.Equal(t, 10*time.Millisecond, entry.breaker.cooldown)
+
+		time.Sleep(15 * time.Millisecond)
+		assert.True(t, entry.available(cfg))
+
+		// The trial request fails: a new, longer quarantine starts.
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+		assert.Equal(t, 20*time.Millisecond, entry.breaker.cooldown)
+		assert.Equal(t, int64(2), entry.QuarantineStreak())
+
+		time.Sleep(25 * time.Millisecond)
+		assert.True(t, entry.available(cfg))
+
+		entry.Stats().RecordSuccess()
+		assert.True(t, entry.available(cfg))
+		assert.Equal(t, int64(2), entry.QuarantineStreak())
+
+		entry.Stats().RecordSuccess()
+		assert.True(t, entry.available(cfg))
+		assert.Equal(t, int64(0), entry.QuarantineStreak())
+
+		entry.Stats().RecordFailed()
+		assert.False(t, entry.available(cfg))
+		assert.Equal(t, 10*time.Millisecond, entry.breaker.cooldown)
+	})
+	t.Run("SlowStartFactor", func(t *testing.T) {
+		entry := newEntry(&mockProxy{id: 1})
+		assert.Equal(t, 1.0, entry.SlowStartFactor())
+
+		entry.warmingSinceUnix.Store(time.Now().UnixNano())
+		assert.Equal(t, 1.0, entry.SlowStartFactor(), "slow start is disabled without a window")
+
+		entry.slowStart = time.Hour
+		assert.InDelta(t, slowStartMinFactor, entry.SlowStartFactor(), 0.01)
+
+		entry.warmingSinceUnix.Store(time.Now().Add(-30 * time.Minute).UnixNano())
+		assert.InDelta(t, 0.55, entry.SlowStartFactor(), 0.01)
+
+		entry.warmingSinceUnix.Store(time.Now().Add(-time.Hour).UnixNano())
+		assert.Equal(t, 1.0, entry.SlowStartFactor())
+	})
 }
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// EnvironmentProxy is the proxy configuration described by the conventional
+// environment variables:
+//
+//   - HTTP_PROXY for http:// targets;
+//   - HTTPS_PROXY for https:// targets;
+//   - ALL_PROXY for either scheme when the specific variable is unset;
+//   - NO_PROXY for targets that must be reached directly (see BypassRules).
+//
+// As in net/http, localhost and loopback addresses are always reached directly.
+// Lower-case variants are honoured when the upper-case variable is unset.
+// As in net/http, HTTP_PROXY is ignored when REQUEST_METHOD is set, since in a
+// CGI environment it may have been injected by the client's "Proxy:" header.
+type EnvironmentProxy struct {
+	HTTP    Proxy
+	HTTPS   Proxy
+	NoProxy *BypassRules
+}
+
+// ProxyFromEnvironment reads the proxy configuration from the environment.
+// Proxy URLs without a scheme are treated as http://. http:// and https:// URLs
+// produce an HTTPProxy, socks4:// and socks4a:// URLs a SOCKS4Proxy and socks5://
+// and socks5h:// URLs a SOCKS5Proxy, all using timeout.
+func ProxyFromEnvironment(timeout time.Duration) (*EnvironmentProxy, error) {
+	return proxyFromEnv(os.Getenv, timeout)
+}
+
+func proxyFromEnv(getenv func(string) string, timeout time.Duration) (*EnvironmentProxy, error) {
+	lookup := func(name string) string {
+		if value := getenv(name); value != "" {
+			return value
+		}
+
+		return getenv(strings.ToLower(name))
+	}
+
+	allProxy := lookup("ALL_PROXY")
+
+	httpProxy := lookup("HTTP_PROXY")
+	if getenv("REQUEST_METHOD") != "" {
+		httpProxy = ""
+	}
+
+	if httpProxy == "" {
+		httpProxy = allProxy
+	}
+
+	httpsProxy := lookup("HTTPS_PROXY")
+	if httpsProxy == "" {
+		httpsProxy = allProxy
+	}
+
+	env := &EnvironmentProxy{NoProxy: ParseBypassRules(lookup("NO_PROXY"))}
+	env.NoProxy.loopback = true
+
+	var err error
+	if env.HTTP, err = ParseProxyURL(httpProxy, timeout); err != nil {
+		return nil, fmt.Errorf("invalid HTTP_PROXY: %w", err)
+	}
+
+	if env.HTTPS, err = ParseProxyURL(httpsProxy, timeout); err != nil {
+		return nil, fmt.Errorf("invalid HTTPS_PROXY: %w", err)
+	}
+
+	return env, nil
+}
+
+// ProxyFor returns the proxy configured for the given target scheme,
+// or nil if requests with that scheme should be sent directly.
+func (e *EnvironmentProxy) ProxyFor(scheme string) Proxy {
+	if strings.EqualFold(scheme, "https") {
+		return e.HTTPS
+	}
+
+	return e.HTTP
+}
+
+// ParseProxyURL builds a Proxy from a proxy URL, choosing the implementation by scheme.
+// A URL without a scheme is treated as http://. An empty string yields a nil Proxy.
+// timeout is applied to proxies that support a dial timeout.
+func ParseProxyURL(rawURL string, timeout time.Duration) (Proxy, error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+
+	parseURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(parseURL.Scheme) {
+	case "http", "https":
+		proxy, err := NewHTTPProxy(rawURL, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return proxy, nil
+	case "socks4", "socks4a":
+		proxy, err := NewSOCKS4Proxy(rawURL, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return proxy, nil
+	case "socks5", "socks5h":
+		proxy, err := NewSOCKS5Proxy(rawURL, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return proxy, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: '%s://'", parseURL.Scheme)
+	}
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyFromEnv(t *testing.T) {
+	t.Parallel()
+
+	env := func(values map[string]string) func(string) string {
+		return func(name string) string { return values[name] }
+	}
+
+	t.Run("EmptyEnvironment", func(t *testing.T) {
+		proxy, err := proxyFromEnv(env(nil), 0)
+
+		assert.NoError(t, err)
+		assert.Nil(t, proxy.HTTP)
+		assert.Nil(t, proxy.HTTPS)
+		assert.False(t, proxy.NoProxy.Match("example.com", "80"))
+		assert.True(t, proxy.NoProxy.Match("localhost", "80"))
+		assert.True(t, proxy.NoProxy.Match("127.0.0.1", "80"))
+		assert.Nil(t, proxy.ProxyFor("https"))
+	})
+
+	t.Run("SchemeSpecificProxies", func(t *testing.T) {
+		proxy, err := proxyFromEnv(env(map[string]string{
+			"HTTP_PROXY":  "http://127.0.0.1:3128",
+			"HTTPS_PROXY": "socks5://127.0.0.1:1080",
+			"NO_PROXY":    "internal.example",
+		}), time.Second)
+
+		assert.NoError(t, err)
+		assert.IsType(t, &HTTPProxy{}, proxy.ProxyFor("http"))
+		assert.IsType(t, &SOCKS5Proxy{}, proxy.ProxyFor("HTTPS"))
+		assert.Equal(t, time.Second, proxy.HTTP.(*HTTPProxy).timeout)
+		assert.True(t, proxy.NoProxy.Match("api.internal.example", "443"))
+	})
+
+	t.Run("LowerCaseAndAllProxyFallback", func(t *testing.T) {
+		proxy, err := proxyFromEnv(env(map[string]string{
+			"https_proxy": "127.0.0.1:3128",
+			"all_proxy":   "socks5h://127.0.0.1:1080",
+		}), 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "http://127.0.0.1:3128", proxy.HTTPS.(*HTTPProxy).url)
+		assert.Equal(t, "socks5h://127.0.0.1:1080", proxy.HTTP.(*SOCKS5Proxy).url)
+	})
+
+	t.Run("HTTPProxyIgnoredInCGI", func(t *testing.T) {
+		proxy, err := proxyFromEnv(env(map[string]string{
+			"HTTP_PROXY":     "http://127.0.0.1:3128",
+			"REQUEST_METHOD": "GET",
+		}), 0)
+
+		assert.NoError(t, err)
+		assert.Nil(t, proxy.HTTP)
+	})
+
+	t.Run("InvalidProxyURL", func(t *testing.T) {
+		proxy, err := proxyFromEnv(env(map[string]string{"HTTPS_PROXY": "ftp://127.0.0.1:21"}), 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+		assert.Contains(t, err.Error(), "HTTPS_PROXY")
+	})
+}
+
+func TestParseProxyURL(t *testing.T) {
+	t.Parallel()
+
+	t.Run("EmptyURL", func(t *testing.T) {
+		proxy, err := ParseProxyURL("", 0)
+
+		assert.NoError(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("SOCKS4", func(t *testing.T) {
+		proxy, err := ParseProxyURL("socks4a://127.0.0.1:1080", time.Second)
+
+		assert.NoError(t, err)
+		assert.True(t, proxy.(*SOCKS4Proxy).remoteDNS)
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		proxy, err := ParseProxyURL("http://proxy.com/bad-percent%", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("UnsupportedScheme", func(t *testing.T) {
+		proxy, err := ParseProxyURL("ftp://127.0.0.1:1080", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+		assert.Contains(t, err.Error(), "unsupported proxy scheme")
+	})
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://127.0.0.1:3128")
+	t.Setenv("NO_PROXY", "localhost")
+
+	proxy, err := ProxyFromEnvironment(0)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, proxy.HTTPS)
+	assert.True(t, proxy.NoProxy.Match("localhost", "443"))
+}
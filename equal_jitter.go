@@ -0,0 +1,67 @@
+package client
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// EqualJitter implements an exponential strategy where half of the delay is
+// deterministic and the other half is randomized.
+//
+// The deterministic half guarantees that delays keep growing with every attempt,
+// while the random half still spreads out clients that started backing off together.
+//
+// Reference: https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type EqualJitter struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// NewEqualJitter initializes a new EqualJitter strategy.
+// If delay or max are less than or equal to zero, package defaults are used.
+// If max is less than delay, max is set to delay.
+func NewEqualJitter(delay, max time.Duration) *EqualJitter {
+	if delay <= 0 {
+		delay = DefaultMinBackoff
+	}
+
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+
+	if max < delay {
+		max = delay
+	}
+
+	return &EqualJitter{baseDelay: delay, maxDelay: max}
+}
+
+// Next calculates the next delay using the Equal Jitter formula:
+// temp = min(cap, base * 2 ^ attempt); sleep = temp / 2 + random_between(0, temp / 2)
+//
+// Arguments:
+//   - attempt: The zero-based retry attempt number.
+//
+// Returns:
+//
+//	A randomized time.Duration between temp / 2 and temp.
+func (e *EqualJitter) Next(attempt int64) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+
+	temp := e.maxDelay
+	// Shifting beyond 62 bits overflows int64, by then the cap is always reached.
+	if attempt < 62 {
+		if delay := e.baseDelay << attempt; delay > 0 && delay < e.maxDelay && delay>>attempt == e.baseDelay {
+			temp = delay
+		}
+	}
+
+	half := int64(temp / 2)
+	if half <= 0 {
+		return temp
+	}
+
+	return time.Duration(half + rand.Int64N(int64(temp)-half+1))
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqualJitter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SuccessInitStrategy", func(t *testing.T) {
+		baseDelay := 1 * time.Second
+		maxDelay := 10 * time.Second
+
+		equalJitter := NewEqualJitter(baseDelay, maxDelay)
+		assert.NotNil(t, equalJitter)
+
+		assert.Equal(t, baseDelay, equalJitter.baseDelay)
+		assert.Equal(t, maxDelay, equalJitter.maxDelay)
+	})
+
+	t.Run("InitStrategyWithDefaultValDelay", func(t *testing.T) {
+		equalJitter := NewEqualJitter(0, -10*time.Second)
+		assert.NotNil(t, equalJitter)
+
+		assert.Equal(t, DefaultMinBackoff, equalJitter.baseDelay)
+		assert.Equal(t, DefaultMaxBackoff, equalJitter.maxDelay)
+	})
+
+	t.Run("InitStrategyWhenMaxLessThanBase", func(t *testing.T) {
+		equalJitter := NewEqualJitter(5*time.Second, 1*time.Second)
+
+		assert.Equal(t, 5*time.Second, equalJitter.maxDelay)
+	})
+
+	t.Run("ReturnsNextInStrictRange", func(t *testing.T) {
+		equalJitter := NewEqualJitter(1*time.Second, time.Minute)
+
+		// attempt 2: temp = 1s * 2^2 = 4s, expected range [2s, 4s]
+		for i := 0; i < 15; i++ {
+			delay := equalJitter.Next(2)
+			assert.True(t, delay >= 2*time.Second, "Delay %v should be >= 2s", delay)
+			assert.True(t, delay <= 4*time.Second, "Delay %v should be <= 4s", delay)
+		}
+	})
+
+	t.Run("ReturnsNextCappedByMaxDelay", func(t *testing.T) {
+		equalJitter := NewEqualJitter(1*time.Second, 5*time.Second)
+
+		for _, attempt := range []int64{3, 10, 63, 1000} {
+			delay := equalJitter.Next(attempt)
+			assert.True(t, delay >= 2500*time.Millisecond, "Delay %v should be >= 2.5s", delay)
+			assert.True(t, delay <= 5*time.Second, "Delay %v should be <= 5s", delay)
+		}
+	})
+
+	t.Run("NegativeAttemptTreatedAsFirst", func(t *testing.T) {
+		equalJitter := NewEqualJitter(1*time.Second, 5*time.Second)
+
+		delay := equalJitter.Next(-1)
+		assert.True(t, delay >= 500*time.Millisecond)
+		assert.True(t, delay <= 1*time.Second)
+	})
+}
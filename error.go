@@ -3,3 +3,27 @@ package client
 import "errors"
 
 var ErrProxyPoolEmpty = errors.New("proxy pool is empty")
+
+var (
+	ErrSOCKS4Rejected          = errors.New("socks4: request rejected")
+	ErrSOCKS4IdentdUnreachable = errors.New("socks4: identd unreachable")
+	ErrSOCKS4IdentdMismatch    = errors.New("socks4: identd user id mismatch")
+)
+
+var (
+	ErrSOCKS5NoAcceptableMethods     = errors.New("socks5: no acceptable authentication methods")
+	ErrSOCKS5AuthFailed              = errors.New("socks5: authentication failed")
+	ErrSOCKS5GeneralFailure          = errors.New("socks5: general server failure")
+	ErrSOCKS5ConnectionNotAllowed    = errors.New("socks5: connection not allowed by ruleset")
+	ErrSOCKS5NetworkUnreachable      = errors.New("socks5: network unreachable")
+	ErrSOCKS5HostUnreachable         = errors.New("socks5: host unreachable")
+	ErrSOCKS5ConnectionRefused       = errors.New("socks5: connection refused")
+	ErrSOCKS5TTLExpired              = errors.New("socks5: TTL expired")
+	ErrSOCKS5CommandNotSupported     = errors.New("socks5: command not supported")
+	ErrSOCKS5AddressTypeNotSupported = errors.New("socks5: address type not supported")
+)
+
+var (
+	ErrProxyAuthRequired  = errors.New("proxy authentication required")
+	ErrProxyConnectFailed = errors.New("proxy refused CONNECT")
+)
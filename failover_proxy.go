@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FailoverProxy tries a list of proxies in order and uses the first one that
+// establishes a connection. It is how PAC results such as
+// "PROXY a:3128; PROXY b:3128; DIRECT" are honoured.
+type FailoverProxy struct {
+	proxies []Proxy
+}
+
+// NewFailoverProxy creates a FailoverProxy trying proxies in the given order.
+func NewFailoverProxy(proxies ...Proxy) *FailoverProxy {
+	return &FailoverProxy{proxies: proxies}
+}
+
+// Proxies returns the proxies in failover order.
+func (f *FailoverProxy) Proxies() []Proxy {
+	return f.proxies
+}
+
+func (f *FailoverProxy) Dial() fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		return f.DialContext(context.Background(), "tcp", addr)
+	}
+}
+
+// DialContext tries the proxies in order until one connects to addr.
+// It stops as soon as ctx ends, without trying the remaining proxies.
+func (f *FailoverProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if len(f.proxies) == 0 {
+		return nil, ErrProxyPoolEmpty
+	}
+
+	errs := make([]error, 0, len(f.proxies))
+	for _, proxy := range f.proxies {
+		conn, err := AsContextDialer(proxy).DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type dialFuncProxy struct {
+	dial  fasthttp.DialFunc
+	calls int
+}
+
+func (d *dialFuncProxy) Dial() fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		d.calls++
+		return d.dial(addr)
+	}
+}
+
+func TestFailoverProxy(t *testing.T) {
+	t.Parallel()
+
+	failing := func() *dialFuncProxy {
+		return &dialFuncProxy{dial: func(addr string) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Err: net.UnknownNetworkError("down")}
+		}}
+	}
+
+	succeeding := func() *dialFuncProxy {
+		return &dialFuncProxy{dial: func(addr string) (net.Conn, error) {
+			client, server := net.Pipe()
+			_ = server.Close()
+			return client, nil
+		}}
+	}
+
+	t.Run("EmptyProxies", func(t *testing.T) {
+		conn, err := NewFailoverProxy().Dial()("example.com:80")
+
+		assert.Nil(t, conn)
+		assert.ErrorIs(t, err, ErrProxyPoolEmpty)
+	})
+
+	t.Run("UsesFirstWorkingProxy", func(t *testing.T) {
+		first, second, third := failing(), succeeding(), succeeding()
+		proxy := NewFailoverProxy(first, second, third)
+		assert.Len(t, proxy.Proxies(), 3)
+
+		conn, err := proxy.Dial()("example.com:80")
+		assert.NoError(t, err)
+		assert.NotNil(t, conn)
+
+		assert.Equal(t, 1, first.calls)
+		assert.Equal(t, 1, second.calls)
+		assert.Equal(t, 0, third.calls)
+	})
+
+	t.Run("JoinsErrorsWhenAllFail", func(t *testing.T) {
+		conn, err := NewFailoverProxy(failing(), failing()).Dial()("example.com:80")
+
+		assert.Nil(t, conn)
+		assert.Error(t, err)
+		assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 2)
+	})
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureRateWindow      = time.Minute
+	defaultFailureRateThreshold   = 0.5
+	defaultFailureRateMinRequests = 10
+
+	// failureRateResolution is the number of snapshots kept per window.
+	failureRateResolution = 10
+)
+
+// FailureRatePolicy marks a proxy unhealthy when the fraction of failed
+// requests over a sliding time window exceeds a threshold.
+//
+// Unlike ConsecutiveFailsPolicy it also catches proxies that fail intermittently,
+// where an occasional success keeps resetting the consecutive counter.
+//
+// The policy derives the window from periodic snapshots of each entry's
+// lifetime Stats counters, so it needs no cooperation from callers beyond
+// the usual RecordSuccess/RecordFailed.
+type FailureRatePolicy struct {
+	window      time.Duration
+	threshold   float64
+	minRequests int64
+
+	// windows maps *Entry to its *rateWindow.
+	windows sync.Map
+}
+
+type rateWindow struct {
+	mutex     sync.Mutex
+	snapshots []counterSnapshot
+}
+
+type counterSnapshot struct {
+	at       time.Time
+	success  int64
+	failures int64
+}
+
+// NewFailureRatePolicy initializes a new FailureRatePolicy.
+//
+// The proxy is considered unhealthy once more than threshold (0.0–1.0) of the
+// requests recorded during the last window have failed. The rule is only applied
+// once at least minRequests requests fall inside the window, so a single early
+// failure does not quarantine a proxy. Non-positive arguments fall back to
+// a 1 minute window, a 0.5 threshold and 10 requests respectively.
+func NewFailureRatePolicy(window time.Duration, threshold float64, minRequests int64) *FailureRatePolicy {
+	if window <= 0 {
+		window = defaultFailureRateWindow
+	}
+
+	if threshold <= 0 {
+		threshold = defaultFailureRateThreshold
+	}
+
+	if minRequests <= 0 {
+		minRequests = defaultFailureRateMinRequests
+	}
+
+	return &FailureRatePolicy{window: window, threshold: threshold, minRequests: minRequests}
+}
+
+func (f *FailureRatePolicy) forget(entry *Entry) {
+	f.windows.Delete(entry)
+}
+
+// Healthy reports whether the failure rate within the window is at most the threshold.
+func (f *FailureRatePolicy) Healthy(entry *Entry, since time.Time) bool {
+	now := time.Now()
+	current := counterSnapshot{at: now, success: entry.stats.SuccessCount(), failures: entry.stats.Failures()}
+
+	value, _ := f.windows.LoadOrStore(entry, &rateWindow{})
+	rates := value.(*rateWindow)
+
+	baseline := rates.observe(current, f.window, since)
+
+	failures := current.failures - baseline.failures
+	requests := current.success - baseline.success + failures
+	if requests < f.minRequests {
+		return true
+	}
+
+	return float64(failures)/float64(requests) <= f.threshold
+}
+
+// observe records current as a new snapshot when the previous one is older than
+// the window resolution, drops snapshots that left the window and returns the
+// oldest snapshot taken no earlier than since.
+func (r *rateWindow) observe(current counterSnapshot, window time.Duration, since time.Time) counterSnapshot {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	start := current.at.Add(-window)
+
+	expired := 0
+	for expired < len(r.snapshots) && r.snapshots[expired].at.Before(start) {
+		expired++
+	}
+	r.snapshots = r.snapshots[expired:]
+
+	if n := len(r.snapshots); n == 0 || current.at.Sub(r.snapshots[n-1].at) >= window/failureRateResolution {
+		r.snapshots = append(r.snapshots, current)
+	}
+
+	for _, snapshot := range r.snapshots {
+		if !snapshot.at.Before(since) {
+			return snapshot
+		}
+	}
+
+	return current
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureRatePolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InitPolicyWithDefaultValues", func(t *testing.T) {
+		policy := NewFailureRatePolicy(0, -1, 0)
+
+		assert.Equal(t, defaultFailureRateWindow, policy.window)
+		assert.Equal(t, defaultFailureRateThreshold, policy.threshold)
+		assert.Equal(t, int64(defaultFailureRateMinRequests), policy.minRequests)
+	})
+
+	t.Run("HealthyBelowMinRequests", func(t *testing.T) {
+		policy := NewFailureRatePolicy(time.Minute, 0.5, 4)
+		entry := newEntry(&mockProxy{id: 1})
+
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordFailed()
+
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+	})
+
+	t.Run("UnhealthyAboveThreshold", func(t *testing.T) {
+		policy := NewFailureRatePolicy(time.Minute, 0.5, 4)
+		entry := newEntry(&mockProxy{id: 1})
+
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+
+		entry.Stats().RecordSuccess()
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordSuccess()
+		entry.Stats().RecordFailed()
+		assert.True(t, policy.Healthy(entry, time.Time{}), "a rate equal to the threshold is still healthy")
+
+		entry.Stats().RecordFailed()
+		assert.False(t, policy.Healthy(entry, time.Time{}))
+	})
+
+	t.Run("IgnoresRequestsBeforeSince", func(t *testing.T) {
+		policy := NewFailureRatePolicy(time.Minute, 0.5, 2)
+		entry := newEntry(&mockProxy{id: 1})
+
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordFailed()
+		assert.False(t, policy.Healthy(entry, time.Time{}))
+
+		assert.True(t, policy.Healthy(entry, time.Now()))
+	})
+
+	t.Run("FailuresLeaveTheWindow", func(t *testing.T) {
+		policy := NewFailureRatePolicy(50*time.Millisecond, 0.5, 2)
+		entry := newEntry(&mockProxy{id: 1})
+
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+
+		entry.Stats().RecordFailed()
+		entry.Stats().RecordFailed()
+		assert.False(t, policy.Healthy(entry, time.Time{}))
+
+		time.Sleep(60 * time.Millisecond)
+
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+	})
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// GatewayConfig describes a rotating gateway and how to build its credentials.
+type GatewayConfig struct {
+	// URL is the gateway address with an http, https, socks5 or socks5h scheme.
+	// The password, if any, is taken from its user info; the username is ignored.
+	URL string
+
+	// UsernameTemplate builds the username sent to the gateway, for example
+	// "customer-acme-session-{session}-country-{country}". {session} is replaced
+	// by the session ID and {name} by Params[name].
+	UsernameTemplate string
+
+	// Params holds the values for the placeholders in UsernameTemplate other than {session}.
+	Params map[string]string
+
+	// SessionTTL is how long a session, and with it the exit IP, is kept.
+	// Zero starts a new session for every connection.
+	SessionTTL time.Duration
+
+	// Timeout limits how long connecting to the gateway may take. Zero means no limit.
+	Timeout time.Duration
+
+	// TLSConfig is used for https:// gateways, see NewHTTPProxyWithTLS.
+	TLSConfig *tls.Config
+}
+
+// GatewayProxy is a Proxy for rotating gateways that pick the exit IP from the
+// username, such as "user-session-abc123-country-us".
+//
+// Every GatewayProxy keeps its own session, so creating several for the same
+// gateway lets it appear as many logical Entries in a Pool, each with its own
+// Stats and sticky exit IP.
+type GatewayProxy struct {
+	cfg      GatewayConfig
+	gateway  *url.URL
+	password string
+
+	// params holds the old, new pairs substituting Params in UsernameTemplate.
+	params []string
+
+	mutex        sync.Mutex
+	session      string
+	sessionStart time.Time
+	proxy        Proxy
+}
+
+// NewGatewayProxy creates a GatewayProxy from cfg. It fails if the URL is invalid
+// or the template uses a placeholder missing from Params.
+func NewGatewayProxy(cfg GatewayConfig) (*GatewayProxy, error) {
+	gateway, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(gateway.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy scheme: expected 'http://', 'https://', 'socks5://' or 'socks5h://', got '%s://'", gateway.Scheme)
+	}
+
+	if cfg.UsernameTemplate == "" {
+		return nil, fmt.Errorf("gateway username template is empty")
+	}
+
+	// Placeholders are checked with empty values, since a value may contain braces.
+	known := []string{"{session}", ""}
+	params := make([]string, 0, 2*len(cfg.Params))
+	for name, value := range cfg.Params {
+		known = append(known, "{"+name+"}", "")
+		params = append(params, "{"+name+"}", value)
+	}
+
+	if rest := strings.NewReplacer(known...).Replace(cfg.UsernameTemplate); strings.ContainsAny(rest, "{}") {
+		return nil, fmt.Errorf("gateway username template has unknown placeholders: '%s'", cfg.UsernameTemplate)
+	}
+
+	proxy := &GatewayProxy{cfg: cfg, gateway: gateway, params: params}
+
+	if gateway.User != nil {
+		proxy.password, _ = gateway.User.Password()
+	}
+
+	return proxy, nil
+}
+
+// NewGatewayProxies creates n GatewayProxy instances sharing cfg, each with its
+// own session, ready to be added to a Pool as independent entries.
+func NewGatewayProxies(cfg GatewayConfig, n int) ([]Proxy, error) {
+	proxies := make([]Proxy, 0, n)
+	for range n {
+		proxy, err := NewGatewayProxy(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies, nil
+}
+
+// Session returns the current sticky session ID, or an empty string if no
+// session is active or it has outlived SessionTTL.
+func (g *GatewayProxy) Session() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.session == "" || time.Since(g.sessionStart) >= g.cfg.SessionTTL {
+		return ""
+	}
+
+	return g.session
+}
+
+// Rotate ends the current session, so the next connection gets a new exit IP.
+func (g *GatewayProxy) Rotate() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.session = ""
+	g.proxy = nil
+}
+
+func (g *GatewayProxy) Dial() fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		return g.DialContext(context.Background(), "tcp", addr)
+	}
+}
+
+// DialContext connects to addr through the gateway using the current session,
+// aborting as soon as ctx ends.
+func (g *GatewayProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxy, err := g.current(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return proxy.(ContextDialer).DialContext(ctx, network, addr)
+}
+
+func (g *GatewayProxy) proxyAddr() string {
+	if g.gateway.Port() != "" {
+		return g.gateway.Host
+	}
+
+	port := "1080"
+	switch strings.ToLower(g.gateway.Scheme) {
+	case "http":
+		port = "80"
+	case "https":
+		port = "443"
+	}
+
+	return net.JoinHostPort(g.gateway.Hostname(), port)
+}
+
+func (g *GatewayProxy) tunnel(conn net.Conn, addr string) (net.Conn, error) {
+	proxy, err := g.current(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return proxy.(tunneler).tunnel(conn, addr)
+}
+
+// current returns the proxy for the session active at now, starting a new
+// session when there is none, it has expired or sessions are not sticky.
+func (g *GatewayProxy) current(now time.Time) (Proxy, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.proxy != nil && g.cfg.SessionTTL > 0 && now.Sub(g.sessionStart) < g.cfg.SessionTTL {
+		return g.proxy, nil
+	}
+
+	session := newSessionID()
+
+	gateway := *g.gateway
+	gateway.User = url.UserPassword(g.username(session), g.password)
+
+	proxy, err := g.newProxy(gateway.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if g.cfg.SessionTTL > 0 {
+		g.session, g.sessionStart, g.proxy = session, now, proxy
+	}
+
+	return proxy, nil
+}
+
+// username fills in UsernameTemplate in a single pass, so that a value
+// containing a placeholder is never expanded in turn.
+func (g *GatewayProxy) username(session string) string {
+	return strings.NewReplacer(g.placeholders(session)...).Replace(g.cfg.UsernameTemplate)
+}
+
+// placeholders returns the old, new pairs of every placeholder. {session} comes
+// first, so it wins over a "session" entry in Params.
+func (g *GatewayProxy) placeholders(session string) []string {
+	return append([]string{"{session}", session}, g.params...)
+}
+
+func (g *GatewayProxy) newProxy(proxyURL string) (Proxy, error) {
+	if strings.HasPrefix(strings.ToLower(g.gateway.Scheme), "socks5") {
+		return NewSOCKS5Proxy(proxyURL, g.cfg.Timeout)
+	}
+
+	return NewHTTPProxyWithTLS(proxyURL, g.cfg.Timeout, g.cfg.TLSConfig)
+}
+
+// newSessionID returns a random session ID of lowercase letters and digits,
+// which every gateway we know of accepts.
+func newSessionID() string {
+	return strconv.FormatUint(rand.Uint64(), 36)
+}
@@ -0,0 +1,103 @@
+package client
+
+import "time"
+
+// HealthPolicy decides whether a proxy may keep receiving traffic.
+//
+// The pool consults the policy only while an entry's circuit is closed:
+// returning false trips the circuit breaker and quarantines the proxy.
+// Recovery from quarantine is driven by the breaker's half-open trials,
+// not by the policy.
+//
+// since is the moment the entry's circuit last closed (zero for an entry that
+// was never quarantined). Observations older than since belong to an incident
+// that has already been dealt with and should be ignored by windowed policies.
+//
+// Healthy is called with the entry's breaker locked, so implementations must
+// only consult the entry's Proxy and Stats and must be safe for concurrent use.
+type HealthPolicy interface {
+	Healthy(entry *Entry, since time.Time) bool
+}
+
+// ConsecutiveFailsPolicy marks a proxy unhealthy once it has failed
+// maxFails times in a row. It is the pool's default policy.
+type ConsecutiveFailsPolicy struct {
+	maxFails int64
+}
+
+// NewConsecutiveFailsPolicy initializes a new ConsecutiveFailsPolicy.
+// If maxFails is less than or equal to zero, 3 is used.
+func NewConsecutiveFailsPolicy(maxFails int64) *ConsecutiveFailsPolicy {
+	if maxFails <= 0 {
+		maxFails = 3
+	}
+
+	return &ConsecutiveFailsPolicy{maxFails: maxFails}
+}
+
+// Healthy reports whether the entry has fewer than maxFails consecutive failures.
+func (c *ConsecutiveFailsPolicy) Healthy(entry *Entry, _ time.Time) bool {
+	return entry.stats.ConsecutiveFails() < c.maxFails
+}
+
+// AndPolicy is healthy only when every wrapped policy is healthy.
+// Use it to quarantine a proxy as soon as any single rule is violated.
+type AndPolicy struct {
+	policies []HealthPolicy
+}
+
+// NewAndPolicy composes policies with logical AND.
+// An AndPolicy without policies is always healthy.
+func NewAndPolicy(policies ...HealthPolicy) *AndPolicy {
+	return &AndPolicy{policies: policies}
+}
+
+// Healthy evaluates the wrapped policies in order and stops at the first unhealthy one.
+func (a *AndPolicy) Healthy(entry *Entry, since time.Time) bool {
+	for _, policy := range a.policies {
+		if !policy.Healthy(entry, since) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *AndPolicy) forget(entry *Entry) {
+	for _, policy := range a.policies {
+		forgetEntry(policy, entry)
+	}
+}
+
+// OrPolicy is healthy when at least one wrapped policy is healthy.
+// Use it to quarantine a proxy only when every rule is violated.
+type OrPolicy struct {
+	policies []HealthPolicy
+}
+
+// NewOrPolicy composes policies with logical OR.
+// An OrPolicy without policies is always healthy.
+func NewOrPolicy(policies ...HealthPolicy) *OrPolicy {
+	return &OrPolicy{policies: policies}
+}
+
+// Healthy evaluates the wrapped policies in order and stops at the first healthy one.
+func (o *OrPolicy) Healthy(entry *Entry, since time.Time) bool {
+	if len(o.policies) == 0 {
+		return true
+	}
+
+	for _, policy := range o.policies {
+		if policy.Healthy(entry, since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *OrPolicy) forget(entry *Entry) {
+	for _, policy := range o.policies {
+		forgetEntry(policy, entry)
+	}
+}
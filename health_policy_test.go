@@ -0,0 +1,72 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticPolicy struct {
+	healthy bool
+	calls   int
+}
+
+func (s *staticPolicy) Healthy(_ *Entry, _ time.Time) bool {
+	s.calls++
+	return s.healthy
+}
+
+func TestConsecutiveFailsPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InitPolicyWithDefaultValue", func(t *testing.T) {
+		policy := NewConsecutiveFailsPolicy(0)
+		assert.Equal(t, int64(3), policy.maxFails)
+	})
+
+	t.Run("UnhealthyAfterMaxFails", func(t *testing.T) {
+		policy := NewConsecutiveFailsPolicy(2)
+		entry := newEntry(&mockProxy{id: 1})
+
+		entry.Stats().RecordFailed()
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+
+		entry.Stats().RecordFailed()
+		assert.False(t, policy.Healthy(entry, time.Time{}))
+
+		entry.Stats().RecordSuccess()
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+	})
+}
+
+func TestCompositePolicy(t *testing.T) {
+	t.Parallel()
+
+	entry := newEntry(&mockProxy{id: 1})
+
+	t.Run("EmptyPoliciesAreHealthy", func(t *testing.T) {
+		assert.True(t, NewAndPolicy().Healthy(entry, time.Time{}))
+		assert.True(t, NewOrPolicy().Healthy(entry, time.Time{}))
+	})
+
+	t.Run("AndPolicy", func(t *testing.T) {
+		healthy := &staticPolicy{healthy: true}
+		unhealthy := &staticPolicy{healthy: false}
+		skipped := &staticPolicy{healthy: true}
+
+		assert.True(t, NewAndPolicy(healthy, healthy).Healthy(entry, time.Time{}))
+		assert.False(t, NewAndPolicy(healthy, unhealthy, skipped).Healthy(entry, time.Time{}))
+		assert.Equal(t, 0, skipped.calls, "evaluation should stop at the first unhealthy policy")
+	})
+
+	t.Run("OrPolicy", func(t *testing.T) {
+		healthy := &staticPolicy{healthy: true}
+		unhealthy := &staticPolicy{healthy: false}
+		skipped := &staticPolicy{healthy: false}
+
+		assert.False(t, NewOrPolicy(unhealthy, unhealthy).Healthy(entry, time.Time{}))
+		assert.True(t, NewOrPolicy(unhealthy, healthy, skipped).Healthy(entry, time.Time{}))
+		assert.Equal(t, 0, skipped.calls, "evaluation should stop at the first healthy policy")
+	})
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// hostClientSweepInterval is how often idle clients and those of removed
+// entries are looked for.
+const hostClientSweepInterval = time.Second
+
+// pooledClient is a client keeping its own keep-alive connections,
+// such as a fasthttp.HostClient or an http.Transport.
+type pooledClient interface {
+	CloseIdleConnections()
+	PendingRequests() int
+}
+
+// hostKey identifies the client a request is sent with: the route it takes
+// and the target it goes to. entry is nil for routes not picked from a Pool.
+type hostKey struct {
+	entry *Entry
+	proxy Proxy
+	host  string
+	isTLS bool
+}
+
+type hostClient[C pooledClient] struct {
+	client   C
+	lastUsed time.Time
+}
+
+// hostClients is a bounded set of clients, one per route and target host, so that
+// keep-alive connections are reused only for requests through the same proxy to
+// the same host.
+//
+// A background sweep closes clients unused for longer than maxIdle and those
+// of entries removed from their pool; it stops while the set is empty. When the
+// set is full, the least recently used client makes room for a new one.
+//
+// A client dropped with requests in flight is kept draining: the sweep closes
+// its connections again once the last request completes, so none outlives it.
+type hostClients[C pooledClient] struct {
+	max       int
+	maxIdle   time.Duration
+	newClient func(key hostKey) C
+
+	mutex    sync.Mutex
+	clients  map[hostKey]*hostClient[C]
+	draining []C
+	sweeping bool
+}
+
+func newHostClients[C pooledClient](maxClients int, maxIdle time.Duration, newClient func(key hostKey) C) *hostClients[C] {
+	return &hostClients[C]{max: maxClients, maxIdle: maxIdle, newClient: newClient, clients: make(map[hostKey]*hostClient[C])}
+}
+
+// get returns the client for key, creating it if needed.
+func (h *hostClients[C]) get(key hostKey) C {
+	now := time.Now()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if client, ok := h.clients[key]; ok {
+		client.lastUsed = now
+		return client.client
+	}
+
+	if len(h.clients) >= h.max {
+		h.evictOldest()
+	}
+
+	client := &hostClient[C]{client: h.newClient(key), lastUsed: now}
+	h.clients[key] = client
+
+	if !h.sweeping {
+		h.sweeping = true
+		go h.sweepLoop()
+	}
+
+	return client.client
+}
+
+// evictOldest drops the least recently used client. Requests in flight on it
+// complete normally; its connections are closed once they become idle.
+// The caller must hold h.mutex.
+func (h *hostClients[C]) evictOldest() {
+	var oldestKey hostKey
+	var oldest *hostClient[C]
+
+	for key, client := range h.clients {
+		if oldest == nil || client.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = key, client
+		}
+	}
+
+	if oldest != nil {
+		h.drop(oldestKey, oldest.client)
+	}
+}
+
+// drop forgets the client kept for key and closes its idle connections. If it
+// still has requests in flight, it is kept draining until they complete.
+// The caller must hold h.mutex.
+func (h *hostClients[C]) drop(key hostKey, client C) {
+	delete(h.clients, key)
+	client.CloseIdleConnections()
+
+	if client.PendingRequests() > 0 {
+		h.draining = append(h.draining, client)
+	}
+}
+
+// sweepLoop sweeps periodically until no client is left, draining ones included.
+func (h *hostClients[C]) sweepLoop() {
+	for {
+		time.Sleep(hostClientSweepInterval)
+		h.sweep(time.Now())
+
+		h.mutex.Lock()
+		if len(h.clients) == 0 && len(h.draining) == 0 {
+			h.sweeping = false
+			h.mutex.Unlock()
+			return
+		}
+		h.mutex.Unlock()
+	}
+}
+
+// sweep closes the clients of removed entries and those without pending
+// requests that have not been used since maxIdle before now, and finishes
+// closing the draining clients whose requests have completed.
+func (h *hostClients[C]) sweep(now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.draining = slices.DeleteFunc(h.draining, func(client C) bool {
+		if client.PendingRequests() > 0 {
+			return false
+		}
+
+		client.CloseIdleConnections()
+		return true
+	})
+
+	for key, client := range h.clients {
+		removed := key.entry != nil && key.entry.Removed()
+		idle := client.client.PendingRequests() == 0 && now.Sub(client.lastUsed) > h.maxIdle
+
+		if removed || idle {
+			h.drop(key, client.client)
+		}
+	}
+}
+
+// closeIdle closes the idle connections of every client and forgets them all.
+func (h *hostClients[C]) closeIdle() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, client := range h.draining {
+		client.CloseIdleConnections()
+	}
+
+	for key, client := range h.clients {
+		h.drop(key, client.client)
+	}
+}
+
+// len returns the number of clients currently kept.
+func (h *hostClients[C]) len() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.clients)
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// countingClient is a pooledClient with settable pending requests that counts
+// how often its idle connections are closed.
+type countingClient struct {
+	pending atomic.Int64
+	closed  atomic.Int64
+}
+
+func (c *countingClient) CloseIdleConnections() { c.closed.Add(1) }
+func (c *countingClient) PendingRequests() int  { return int(c.pending.Load()) }
+
+func TestHostClients(t *testing.T) {
+	t.Parallel()
+
+	newClient := func(key hostKey) *fasthttp.HostClient {
+		return &fasthttp.HostClient{Addr: key.host}
+	}
+
+	t.Run("OneClientPerRouteAndHost", func(t *testing.T) {
+		hosts := newHostClients(10, time.Minute, newClient)
+		entry := newEntry(&mockProxy{id: 1})
+
+		first := hosts.get(hostKey{entry: entry, proxy: entry.Proxy(), host: "a.example:80"})
+		assert.Same(t, first, hosts.get(hostKey{entry: entry, proxy: entry.Proxy(), host: "a.example:80"}))
+
+		other := newEntry(entry.Proxy())
+		assert.NotSame(t, first, hosts.get(hostKey{entry: other, proxy: other.Proxy(), host: "a.example:80"}))
+		assert.NotSame(t, first, hosts.get(hostKey{entry: entry, proxy: entry.Proxy(), host: "a.example:80", isTLS: true}))
+		assert.NotSame(t, first, hosts.get(hostKey{entry: entry, proxy: entry.Proxy(), host: "b.example:80"}))
+		assert.Equal(t, 4, hosts.len())
+	})
+
+	t.Run("EvictsLeastRecentlyUsed", func(t *testing.T) {
+		hosts := newHostClients(2, time.Minute, newClient)
+		proxy := &mockProxy{id: 1}
+
+		a := hosts.get(hostKey{proxy: proxy, host: "a.example:80"})
+		hosts.get(hostKey{proxy: proxy, host: "b.example:80"})
+		time.Sleep(time.Millisecond)
+		hosts.get(hostKey{proxy: proxy, host: "a.example:80"})
+		hosts.get(hostKey{proxy: proxy, host: "c.example:80"})
+
+		assert.Equal(t, 2, hosts.len())
+		assert.Same(t, a, hosts.get(hostKey{proxy: proxy, host: "a.example:80"}))
+		assert.Contains(t, hosts.clients, hostKey{proxy: proxy, host: "c.example:80"})
+		assert.NotContains(t, hosts.clients, hostKey{proxy: proxy, host: "b.example:80"})
+	})
+
+	t.Run("SweepClosesIdleAndRemoved", func(t *testing.T) {
+		targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer targetServer.Close()
+
+		direct, err := NewDirectProxy("", time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{direct, direct}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxIdleConnDuration: time.Minute})
+		assert.NoError(t, err)
+
+		doRequest(t, client, targetServer.URL)
+		doRequest(t, client, targetServer.URL)
+		assert.Equal(t, 2, client.hosts.len())
+
+		entries := pool.Entries()
+		removed := client.hosts.clients[hostKey{entry: entries[0], proxy: direct, host: targetServer.Listener.Addr().String()}].client
+		assert.Equal(t, 1, removed.ConnsCount())
+
+		client.hosts.sweep(time.Now())
+		assert.Equal(t, 2, client.hosts.len())
+
+		pool.Remove(entries[0])
+		client.hosts.sweep(time.Now())
+
+		assert.Equal(t, 1, client.hosts.len())
+		assert.Equal(t, 0, removed.ConnsCount())
+
+		client.hosts.sweep(time.Now().Add(2 * time.Minute))
+		assert.Equal(t, 0, client.hosts.len())
+	})
+	t.Run("BusyClientDrainedAfterRequestsComplete", func(t *testing.T) {
+		hosts := newHostClients(1, time.Minute, func(hostKey) *countingClient { return &countingClient{} })
+		proxy := &mockProxy{id: 1}
+
+		busy := hosts.get(hostKey{proxy: proxy, host: "a.example:80"})
+		busy.pending.Store(1)
+
+		idle := hosts.get(hostKey{proxy: proxy, host: "b.example:80"})
+		assert.Equal(t, int64(1), busy.closed.Load(), "evicting closes the idle connections")
+		assert.Equal(t, []*countingClient{busy}, hosts.draining)
+
+		hosts.closeIdle()
+		assert.Equal(t, int64(2), busy.closed.Load())
+		assert.Equal(t, int64(1), idle.closed.Load())
+		assert.Equal(t, 0, hosts.len())
+
+		hosts.sweep(time.Now())
+		assert.Equal(t, int64(2), busy.closed.Load(), "kept while a request is in flight")
+		assert.Len(t, hosts.draining, 1)
+
+		busy.pending.Store(0)
+		hosts.sweep(time.Now())
+		assert.Equal(t, int64(3), busy.closed.Load(), "closed again once the request completed")
+		assert.Empty(t, hosts.draining)
+	})
+}
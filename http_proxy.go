@@ -1,37 +1,370 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/valyala/fasthttp"
-	"github.com/valyala/fasthttp/fasthttpproxy"
 )
 
+// ConnectResponse is the proxy's response to a CONNECT request.
+// Rotating gateways often report details such as the exit IP in its headers.
+type ConnectResponse struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// ConnectResponseOf returns the CONNECT response behind a connection returned by
+// HTTPProxy.Dial, or nil if conn was not established through an HTTPProxy.
+func ConnectResponseOf(conn net.Conn) *ConnectResponse {
+	if tunnel, ok := conn.(interface{ ConnectResponse() *ConnectResponse }); ok {
+		return tunnel.ConnectResponse()
+	}
+
+	return nil
+}
+
+// ConnectError reports a CONNECT request the proxy answered with a status other
+// than 200. It unwraps to ErrProxyAuthRequired for 407 and to ErrProxyConnectFailed
+// otherwise.
+type ConnectError struct {
+	ProxyAddr string
+	Response  ConnectResponse
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("could not connect to proxyAddr: %s status code: %d", e.ProxyAddr, e.Response.StatusCode)
+}
+
+func (e *ConnectError) Unwrap() error {
+	if e.Response.StatusCode == http.StatusProxyAuthRequired {
+		return ErrProxyAuthRequired
+	}
+
+	return ErrProxyConnectFailed
+}
+
+// HTTPProxy is a Proxy that tunnels connections through an HTTP proxy using CONNECT.
+//
+// With an https:// URL the connection to the proxy itself is wrapped in TLS before
+// CONNECT is sent, so credentials in Proxy-Authorization never travel in plaintext.
 type HTTPProxy struct {
-	url     string
-	timeout time.Duration
+	url        string
+	addr       string
+	auth       string
+	tlsConfig  *tls.Config
+	timeout    time.Duration
+	header     http.Header
+	headerFunc func(addr string) http.Header
+	onConnect  func(addr string, res *ConnectResponse)
+
+	// dialOnce builds dial, dialer and staticHeader on first use,
+	// so they are not rebuilt for every connection.
+	dialOnce     sync.Once
+	dial         fasthttp.DialFunc
+	dialer       *net.Dialer
+	staticHeader []byte
 }
 
+// connectReaderSize is the buffer size of pooled CONNECT response readers.
+// A response header that does not fit is read again with a larger reader,
+// up to maxConnectHeaderSize, which matches net/http's http.DefaultMaxHeaderBytes.
+const (
+	connectReaderSize    = 4096
+	maxConnectHeaderSize = 1 << 20
+)
+
+// connectReaders recycles the readers CONNECT responses are parsed with.
+var connectReaders = sync.Pool{New: func() any { return bufio.NewReaderSize(nil, connectReaderSize) }}
+
+// NewHTTPProxy creates an HTTPProxy from an http:// or https:// URL.
+// A non-zero timeout limits how long connecting to the proxy, the TLS handshake
+// and the CONNECT exchange may take. https:// proxies are verified against the
+// system roots; use NewHTTPProxyWithTLS to configure TLS.
 func NewHTTPProxy(proxyURL string, timeout time.Duration) (*HTTPProxy, error) {
+	return NewHTTPProxyWithTLS(proxyURL, timeout, nil)
+}
+
+// NewHTTPProxyWithTLS creates an HTTPProxy that uses tlsConfig for the connection
+// to an https:// proxy, for example to trust a private CA bundle, present a client
+// certificate or override the server name. A nil tlsConfig uses the defaults.
+// If tlsConfig.ServerName is empty, the proxy host name is used for SNI and verification.
+// tlsConfig is ignored for http:// proxies.
+func NewHTTPProxyWithTLS(proxyURL string, timeout time.Duration, tlsConfig *tls.Config) (*HTTPProxy, error) {
 	parseURL, err := url.Parse(proxyURL)
 	if err != nil {
 		return nil, err
 	}
 
-	if !strings.HasPrefix(parseURL.Scheme, "http") {
+	scheme := strings.ToLower(parseURL.Scheme)
+	if scheme != "http" && scheme != "https" {
 		return nil, fmt.Errorf("invalid proxy scheme: expected 'http://' or 'https://', got '%s://'", parseURL.Scheme)
 	}
 
-	return &HTTPProxy{url: proxyURL, timeout: timeout}, nil
+	proxy := &HTTPProxy{url: proxyURL, addr: parseURL.Host, timeout: timeout}
+	if parseURL.User != nil {
+		proxy.auth = base64.StdEncoding.EncodeToString([]byte(parseURL.User.String()))
+	}
+
+	if scheme == "https" {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		} else {
+			tlsConfig = tlsConfig.Clone()
+		}
+
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = parseURL.Hostname()
+		}
+
+		proxy.tlsConfig = tlsConfig
+	}
+
+	if parseURL.Port() == "" && parseURL.Hostname() != "" {
+		port := "80"
+		if scheme == "https" {
+			port = "443"
+		}
+
+		proxy.addr = net.JoinHostPort(parseURL.Hostname(), port)
+	}
+
+	return proxy, nil
+}
+
+// SetConnectHeader sets headers sent with every CONNECT request, such as the
+// session or country targeting headers rotating gateways expect. Values set here
+// replace headers of the same name derived from the URL, like Proxy-Authorization.
+// It must be called before the proxy is used.
+func (h *HTTPProxy) SetConnectHeader(header http.Header) {
+	h.header = header.Clone()
+}
+
+// SetConnectHeaderFunc sets a callback returning additional headers for the
+// CONNECT request to addr. Its values replace static headers of the same name.
+// It must be called before the proxy is used.
+func (h *HTTPProxy) SetConnectHeaderFunc(fn func(addr string) http.Header) {
+	h.headerFunc = fn
 }
 
+// OnConnect sets a callback invoked with the proxy's response to every CONNECT
+// request, whether or not the tunnel was established.
+// It must be called before the proxy is used.
+func (h *HTTPProxy) OnConnect(fn func(addr string, res *ConnectResponse)) {
+	h.onConnect = fn
+}
+
+// Dial returns a DialFunc establishing tunnels through the proxy. The connections
+// it returns carry the CONNECT response, see ConnectResponseOf; a refused CONNECT
+// is reported as a *ConnectError.
 func (h *HTTPProxy) Dial() fasthttp.DialFunc {
+	h.dialOnce.Do(h.init)
+	return h.dial
+}
+
+// init builds everything that does not change between connections: the dial
+// function, the TCP dialer and the CONNECT headers derived from the URL and
+// SetConnectHeader, rendered once.
+func (h *HTTPProxy) init() {
+	h.dialer = &net.Dialer{Timeout: h.timeout}
+	h.dial = func(addr string) (net.Conn, error) {
+		return h.DialContext(context.Background(), "tcp", addr)
+	}
+
+	var buf bytes.Buffer
+	_ = h.baseHeader().Write(&buf)
+	h.staticHeader = buf.Bytes()
+}
+
+// baseHeader returns the CONNECT headers that are the same for every request.
+func (h *HTTPProxy) baseHeader() http.Header {
+	header := make(http.Header, len(h.header)+1)
+	if h.auth != "" {
+		header.Set("Proxy-Authorization", "Basic "+h.auth)
+	}
+
+	for key, values := range h.header {
+		header[key] = values
+	}
+
+	return header
+}
+
+// DialContext establishes a tunnel to addr, aborting as soon as ctx ends.
+func (h *HTTPProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	h.dialOnce.Do(h.init)
+
+	conn, err := h.dialer.DialContext(ctx, "tcp", h.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return handshakeContext(ctx, conn, func(conn net.Conn) (net.Conn, error) {
+		return h.tunnel(conn, addr)
+	})
+}
+
+func (h *HTTPProxy) proxyAddr() string {
+	return h.addr
+}
+
+// tunnel establishes a tunnel to addr over conn, an open connection to the proxy,
+// wrapping it in TLS first for https:// proxies. The timeout applies to the whole exchange.
+func (h *HTTPProxy) tunnel(conn net.Conn, addr string) (net.Conn, error) {
 	if h.timeout > 0 {
-		return fasthttpproxy.FasthttpHTTPDialerDualStackTimeout(h.url, h.timeout)
+		_ = conn.SetDeadline(time.Now().Add(h.timeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if h.tlsConfig != nil {
+		tlsConn := tls.Client(conn, h.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+
+		conn = tlsConn
+	}
+
+	return h.connect(conn, addr)
+}
+
+// connect asks the proxy to open a tunnel to addr over conn.
+func (h *HTTPProxy) connect(conn net.Conn, addr string) (net.Conn, error) {
+	h.dialOnce.Do(h.init)
+
+	request := make([]byte, 0, 64+2*len(addr)+len(h.staticHeader))
+	request = append(request, "CONNECT "...)
+	request = append(request, addr...)
+	request = append(request, " HTTP/1.1\r\nHost: "...)
+	request = append(request, addr...)
+	request = append(request, "\r\n"...)
+
+	if h.headerFunc == nil {
+		request = append(request, h.staticHeader...)
+	} else {
+		header := h.baseHeader()
+		for key, values := range h.headerFunc(addr) {
+			header[http.CanonicalHeaderKey(key)] = values
+		}
+
+		buf := bytes.NewBuffer(request)
+		_ = header.Write(buf)
+		request = buf.Bytes()
+	}
+
+	request = append(request, "\r\n"...)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	reader := connectReaders.Get().(*bufio.Reader)
+	reader.Reset(conn)
+
+	res, reader, err := readConnectResponse(conn, reader)
+	if err != nil {
+		releaseConnectReader(reader)
+		return nil, err
+	}
+
+	if h.onConnect != nil {
+		h.onConnect(addr, res)
+	}
+
+	if res.StatusCode != fasthttp.StatusOK {
+		releaseConnectReader(reader)
+		return nil, &ConnectError{ProxyAddr: h.addr, Response: *res}
+	}
+
+	tunnel := &tunnelConn{Conn: conn, response: res}
+	if reader.Buffered() > 0 {
+		// The reader holds tunneled bytes and now belongs to the connection.
+		tunnel.reader = reader
+	} else {
+		releaseConnectReader(reader)
+	}
+
+	return tunnel, nil
+}
+
+// readConnectResponse parses the CONNECT response from reader, which reads conn.
+// If the header does not fit, reader is released and replaced by a larger one
+// holding the bytes it had buffered; the reader in use is returned either way.
+func readConnectResponse(conn net.Conn, reader *bufio.Reader) (*ConnectResponse, *bufio.Reader, error) {
+	var header fasthttp.ResponseHeader
+	for {
+		err := header.Read(reader)
+		if err == nil {
+			break
+		}
+
+		var small *fasthttp.ErrSmallBuffer
+		if !errors.As(err, &small) || reader.Size() >= maxConnectHeaderSize {
+			return nil, reader, err
+		}
+
+		// A failed Read consumes nothing, so the buffered bytes start the response.
+		buffered, _ := reader.Peek(reader.Buffered())
+		grown := bufio.NewReaderSize(io.MultiReader(bytes.NewReader(bytes.Clone(buffered)), conn), 2*reader.Size())
+		releaseConnectReader(reader)
+		reader = grown
+	}
+
+	res := &ConnectResponse{StatusCode: header.StatusCode(), Header: make(http.Header)}
+	for key, value := range header.All() {
+		res.Header.Add(string(key), string(value))
 	}
 
-	return fasthttpproxy.FasthttpHTTPDialer(h.url)
+	return res, reader, nil
+}
+
+// releaseConnectReader returns reader to the pool unless it was grown for a large header.
+func releaseConnectReader(reader *bufio.Reader) {
+	if reader.Size() != connectReaderSize {
+		return
+	}
+
+	reader.Reset(nil)
+	connectReaders.Put(reader)
+}
+
+// tunnelConn is a connection tunneled through an HTTP proxy. Its first reads are
+// served from reader, if set, which may already hold bytes the proxy sent right
+// after its CONNECT response.
+type tunnelConn struct {
+	net.Conn
+	reader   *bufio.Reader
+	response *ConnectResponse
+}
+
+func (c *tunnelConn) Read(p []byte) (int, error) {
+	if c.reader != nil {
+		if c.reader.Buffered() > 0 {
+			return c.reader.Read(p)
+		}
+
+		c.reader = nil
+	}
+
+	return c.Conn.Read(p)
+}
+
+// ConnectResponse returns the proxy's response to the CONNECT request.
+func (c *tunnelConn) ConnectResponse() *ConnectResponse {
+	return c.response
 }
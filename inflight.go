@@ -0,0 +1,51 @@
+package client
+
+import (
+	"math"
+	"time"
+)
+
+// inFlight estimates the requests in flight through an entry as the picks handed
+// out minus the results recorded in its Stats. Picks that never get a result,
+// such as cancelled requests, would otherwise count forever, so the estimate
+// decays towards zero with the given time constant.
+//
+// inFlight is not safe for concurrent use; its owner must serialize access.
+type inFlight struct {
+	decay time.Duration
+
+	pending float64
+	stamp   time.Time
+
+	// completed is the number of results Stats held when pending was last settled.
+	completed int64
+}
+
+func newInFlight(entry *Entry, decay time.Duration) inFlight {
+	return inFlight{decay: decay, completed: entry.stats.SuccessCount() + entry.stats.Failures()}
+}
+
+// add records a pick made at now.
+func (f *inFlight) add(now time.Time) {
+	f.settle(now)
+	f.pending++
+}
+
+// count deducts the results entry recorded since the last call
+// and returns the estimate at now.
+func (f *inFlight) count(entry *Entry, now time.Time) float64 {
+	completed := entry.stats.SuccessCount() + entry.stats.Failures()
+	f.pending = max(f.pending-float64(completed-f.completed), 0)
+	f.completed = completed
+	f.settle(now)
+
+	return f.pending
+}
+
+// settle decays pending up to now.
+func (f *inFlight) settle(now time.Time) {
+	if elapsed := now.Sub(f.stamp); elapsed > 0 {
+		f.pending *= math.Exp(-float64(elapsed) / float64(f.decay))
+		f.stamp = now
+	}
+}
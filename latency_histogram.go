@@ -0,0 +1,92 @@
+package client
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// histogramSubBits is the number of mantissa bits kept per power of two.
+	// Three bits give 8 sub-buckets per octave, bounding the relative error
+	// of any reported percentile to 12.5%.
+	histogramSubBits    = 3
+	histogramSubBuckets = 1 << histogramSubBits
+
+	// histogramBuckets covers every non-negative int64 nanosecond value.
+	histogramBuckets = (64 - histogramSubBits) * histogramSubBuckets
+)
+
+// latencyHistogram is a lock-free log-linear histogram of latencies in nanoseconds.
+//
+// Values are grouped by their power of two and then split linearly into
+// histogramSubBuckets sub-buckets, in the spirit of HDR histograms. Recording
+// is a single atomic increment, so it adds no contention on the request path.
+type latencyHistogram struct {
+	counts [histogramBuckets]atomic.Int64
+}
+
+// record adds a single latency sample. Negative values are recorded as zero.
+func (h *latencyHistogram) record(latency time.Duration) {
+	h.counts[histogramIndex(max(int64(latency), 0))].Add(1)
+}
+
+// percentile returns the latency below which the fraction q (0.0–1.0) of samples fall.
+// The value reported is the upper bound of the bucket that contains the percentile.
+// Returns 0 if no samples have been recorded.
+func (h *latencyHistogram) percentile(q float64) time.Duration {
+	var counts [histogramBuckets]int64
+	var total int64
+
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+
+	if total == 0 {
+		return 0
+	}
+
+	target := max(int64(math.Ceil(min(max(q, 0), 1)*float64(total))), 1)
+
+	var cumulative int64
+	for i, count := range counts {
+		cumulative += count
+		if cumulative >= target {
+			return time.Duration(histogramUpperBound(i))
+		}
+	}
+
+	return time.Duration(histogramUpperBound(histogramBuckets - 1))
+}
+
+// histogramIndex maps a non-negative value to its bucket index.
+// Values below histogramSubBuckets get an exact bucket each.
+func histogramIndex(value int64) int {
+	if value < histogramSubBuckets {
+		return int(value)
+	}
+
+	shift := bits.Len64(uint64(value)) - 1 - histogramSubBits
+	mantissa := int(value >> shift)
+
+	return (shift+1)*histogramSubBuckets + mantissa - histogramSubBuckets
+}
+
+// histogramUpperBound returns the largest value that maps to the bucket at index.
+func histogramUpperBound(index int) int64 {
+	if index < histogramSubBuckets {
+		return int64(index)
+	}
+
+	shift := index/histogramSubBuckets - 1
+	mantissa := int64(histogramSubBuckets + index%histogramSubBuckets)
+
+	upper := (mantissa+1)<<shift - 1
+	if upper < 0 {
+		return math.MaxInt64
+	}
+
+	return upper
+}
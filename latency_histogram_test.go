@@ -0,0 +1,62 @@
+package client
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	t.Parallel()
+
+	t.Run("EmptyHistogram", func(t *testing.T) {
+		histogram := &latencyHistogram{}
+		assert.Equal(t, time.Duration(0), histogram.percentile(0.5))
+	})
+
+	t.Run("IndexAndUpperBoundAreConsistent", func(t *testing.T) {
+		values := []int64{0, 1, 7, 8, 9, 15, 16, 17, 1000, 123456789, math.MaxInt64}
+
+		for _, value := range values {
+			index := histogramIndex(value)
+			assert.True(t, index < histogramBuckets, "index %d out of range for %d", index, value)
+			assert.GreaterOrEqual(t, histogramUpperBound(index), value)
+
+			if index > 0 {
+				assert.Less(t, histogramUpperBound(index-1), value)
+			}
+		}
+	})
+
+	t.Run("PercentilesWithinRelativeError", func(t *testing.T) {
+		histogram := &latencyHistogram{}
+		for i := 1; i <= 100; i++ {
+			histogram.record(time.Duration(i) * time.Millisecond)
+		}
+
+		cases := map[float64]time.Duration{0.5: 50 * time.Millisecond, 0.9: 90 * time.Millisecond, 0.99: 99 * time.Millisecond}
+		for q, expected := range cases {
+			actual := histogram.percentile(q)
+			assert.GreaterOrEqual(t, actual, expected)
+			assert.LessOrEqual(t, float64(actual), float64(expected)*1.125, "p%v = %v", q*100, actual)
+		}
+	})
+
+	t.Run("SubMillisecondPrecision", func(t *testing.T) {
+		histogram := &latencyHistogram{}
+		histogram.record(300 * time.Microsecond)
+
+		actual := histogram.percentile(0.5)
+		assert.GreaterOrEqual(t, actual, 300*time.Microsecond)
+		assert.Less(t, actual, 340*time.Microsecond)
+	})
+
+	t.Run("NegativeValuesRecordedAsZero", func(t *testing.T) {
+		histogram := &latencyHistogram{}
+		histogram.record(-time.Second)
+
+		assert.Equal(t, time.Duration(0), histogram.percentile(1))
+	})
+}
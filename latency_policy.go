@@ -0,0 +1,54 @@
+package client
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	defaultLatencyQuantile   = 0.95
+	defaultLatencyMinSamples = 20
+)
+
+// LatencyPolicy marks a proxy unhealthy when a latency percentile of its
+// recent requests exceeds an SLO threshold.
+//
+// It is meant for proxies that technically work but have become too slow to be
+// useful, which a failure-based policy would never quarantine.
+type LatencyPolicy struct {
+	quantile   float64
+	threshold  time.Duration
+	minSamples int
+}
+
+// NewLatencyPolicy initializes a new LatencyPolicy.
+//
+// The proxy is considered unhealthy once the given quantile (for example 0.95 for
+// p95) of its recent latency samples is above threshold. The rule is only applied
+// once at least minSamples samples are available. A quantile outside (0, 1] falls
+// back to 0.95, and a non-positive minSamples falls back to 20.
+func NewLatencyPolicy(quantile float64, threshold time.Duration, minSamples int) *LatencyPolicy {
+	if quantile <= 0 || quantile > 1 {
+		quantile = defaultLatencyQuantile
+	}
+
+	if minSamples <= 0 {
+		minSamples = defaultLatencyMinSamples
+	}
+
+	return &LatencyPolicy{quantile: quantile, threshold: threshold, minSamples: minSamples}
+}
+
+// Healthy reports whether the configured latency percentile is within the threshold.
+func (l *LatencyPolicy) Healthy(entry *Entry, since time.Time) bool {
+	samples := entry.stats.recentLatencies(since)
+	if len(samples) < l.minSamples {
+		return true
+	}
+
+	slices.Sort(samples)
+	index := int(math.Ceil(l.quantile*float64(len(samples)))) - 1
+
+	return samples[max(index, 0)] <= l.threshold
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InitPolicyWithDefaultValues", func(t *testing.T) {
+		policy := NewLatencyPolicy(1.5, time.Second, 0)
+
+		assert.Equal(t, defaultLatencyQuantile, policy.quantile)
+		assert.Equal(t, defaultLatencyMinSamples, policy.minSamples)
+		assert.Equal(t, time.Second, policy.threshold)
+	})
+
+	t.Run("HealthyBelowMinSamples", func(t *testing.T) {
+		policy := NewLatencyPolicy(0.95, 100*time.Millisecond, 5)
+		entry := newEntry(&mockProxy{id: 1})
+
+		entry.Stats().RecordLatency(1000 * time.Millisecond)
+
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+	})
+
+	t.Run("UnhealthyWhenPercentileAboveThreshold", func(t *testing.T) {
+		policy := NewLatencyPolicy(0.9, 100*time.Millisecond, 10)
+		entry := newEntry(&mockProxy{id: 1})
+
+		for i := 0; i < 9; i++ {
+			entry.Stats().RecordLatency(50 * time.Millisecond)
+		}
+		entry.Stats().RecordLatency(500 * time.Millisecond)
+
+		// p90 of nine 50ms samples and one 500ms sample is 50ms.
+		assert.True(t, policy.Healthy(entry, time.Time{}))
+
+		entry.Stats().RecordLatency(500 * time.Millisecond)
+		assert.False(t, policy.Healthy(entry, time.Time{}))
+	})
+
+	t.Run("IgnoresSamplesBeforeSince", func(t *testing.T) {
+		policy := NewLatencyPolicy(0.95, 100*time.Millisecond, 1)
+		entry := newEntry(&mockProxy{id: 1})
+
+		entry.Stats().RecordLatency(500 * time.Millisecond)
+		assert.False(t, policy.Healthy(entry, time.Time{}))
+
+		since := time.Now()
+		entry.Stats().RecordLatency(50 * time.Millisecond)
+		assert.True(t, policy.Healthy(entry, since))
+	})
+}
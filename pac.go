@@ -0,0 +1,464 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	defaultPACCacheTTL = 5 * time.Minute
+
+	// pacCacheSize bounds the number of cached decisions.
+	pacCacheSize = 1024
+
+	// pacEvalTimeout bounds a single FindProxyForURL call,
+	// so a broken script cannot hang requests forever.
+	pacEvalTimeout = time.Second
+)
+
+var ErrPACInvalid = errors.New("pac script does not define FindProxyForURL")
+
+// PAC evaluates a proxy auto-config script and maps its decisions onto Proxy
+// implementations.
+//
+// The script runs in an embedded pure-Go JavaScript interpreter with the standard
+// PAC helper functions (isPlainHostName, dnsDomainIs, shExpMatch, isInNet,
+// dnsResolve, myIpAddress, weekdayRange, timeRange, ...). Results are cached per
+// target scheme and host, and each distinct result string maps to one Proxy
+// instance, so connections are reused across requests that share a route.
+//
+// A result listing several entries, such as "PROXY a:3128; SOCKS5 b:1080; DIRECT",
+// is returned as a FailoverProxy trying them in PAC order.
+//
+// PAC is safe for concurrent use; script evaluation itself is serialized.
+type PAC struct {
+	mutex    sync.Mutex
+	runtime  *goja.Runtime
+	findFunc goja.Callable
+
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	// evalCtx ends when the evaluation in progress times out. The DNS helpers
+	// use it, as a lookup blocked in Go cannot be interrupted like the script.
+	evalCtx context.Context
+
+	// hosts caches decisions per target, up to pacCacheSize of them.
+	cacheMutex sync.Mutex
+	hosts      map[pacCacheKey]pacCacheItem
+
+	// routes maps a PAC result string to the Proxy built for it.
+	routes sync.Map
+}
+
+type pacCacheKey struct {
+	scheme string
+	host   string
+}
+
+type pacCacheItem struct {
+	proxy   Proxy
+	expires time.Time
+}
+
+// NewPAC compiles a PAC script. timeout is the dial timeout of the proxies built
+// from its results. cacheTTL is how long a decision is cached per target; zero
+// falls back to 5 minutes and a negative value disables caching.
+func NewPAC(script string, timeout, cacheTTL time.Duration) (*PAC, error) {
+	if cacheTTL == 0 {
+		cacheTTL = defaultPACCacheTTL
+	}
+
+	pac := &PAC{runtime: goja.New(), timeout: timeout, cacheTTL: cacheTTL, hosts: make(map[pacCacheKey]pacCacheItem)}
+	registerPACHelpers(pac.runtime, pac.evalContext)
+
+	if _, err := pac.runtime.RunString(pacHelpersScript); err != nil {
+		return nil, err
+	}
+
+	err := pac.evaluate(func() error {
+		_, err := pac.runtime.RunString(script)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pac script: %w", err)
+	}
+
+	findFunc, ok := goja.AssertFunction(pac.runtime.Get("FindProxyForURL"))
+	if !ok {
+		return nil, ErrPACInvalid
+	}
+
+	pac.findFunc = findFunc
+	return pac, nil
+}
+
+// FindProxy evaluates the script for targetURL and returns its raw result,
+// for example "PROXY proxy.corp:3128; DIRECT". Results are not cached.
+func (p *PAC) FindProxy(targetURL string) (string, error) {
+	parseURL, err := url.Parse(targetURL)
+	if err != nil {
+		return "", err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var value goja.Value
+	err = p.evaluate(func() error {
+		var err error
+		value, err = p.findFunc(goja.Undefined(), p.runtime.ToValue(targetURL), p.runtime.ToValue(parseURL.Hostname()))
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("pac evaluation: %w", err)
+	}
+
+	return value.String(), nil
+}
+
+// evaluate runs fn, which calls into the script, within pacEvalTimeout.
+// The caller must hold p.mutex, unless the PAC is still being built.
+func (p *PAC) evaluate(fn func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pacEvalTimeout)
+	defer cancel()
+
+	p.evalCtx = ctx
+	defer func() { p.evalCtx = nil }()
+
+	timer := time.AfterFunc(pacEvalTimeout, func() { p.runtime.Interrupt("pac evaluation timed out") })
+	defer timer.Stop()
+	defer p.runtime.ClearInterrupt()
+
+	return fn()
+}
+
+// evalContext returns the context of the evaluation in progress.
+func (p *PAC) evalContext() context.Context {
+	if p.evalCtx == nil {
+		return context.Background()
+	}
+
+	return p.evalCtx
+}
+
+// ProxyFor returns the Proxy requests to targetURL should go through.
+// The decision is cached per scheme and host for the configured TTL.
+func (p *PAC) ProxyFor(targetURL string) (Proxy, error) {
+	parseURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	key := pacCacheKey{scheme: parseURL.Scheme, host: parseURL.Host}
+	if proxy := p.cached(key, time.Now()); proxy != nil {
+		return proxy, nil
+	}
+
+	result, err := p.FindProxy(targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := p.route(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cacheTTL > 0 {
+		p.store(key, proxy, time.Now())
+	}
+
+	return proxy, nil
+}
+
+// cached returns the decision cached for key, or nil if there is none or it
+// expired before now.
+func (p *PAC) cached(key pacCacheKey, now time.Time) Proxy {
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+
+	if item, ok := p.hosts[key]; ok && now.Before(item.expires) {
+		return item.proxy
+	}
+
+	return nil
+}
+
+// store caches proxy for key. When the cache is full, expired decisions are
+// dropped first, then the one closest to expiry.
+func (p *PAC) store(key pacCacheKey, proxy Proxy, now time.Time) {
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+
+	if _, ok := p.hosts[key]; !ok && len(p.hosts) >= pacCacheSize {
+		var oldestKey pacCacheKey
+		var oldest time.Time
+
+		for cachedKey, item := range p.hosts {
+			if !now.Before(item.expires) {
+				delete(p.hosts, cachedKey)
+			} else if oldest.IsZero() || item.expires.Before(oldest) {
+				oldestKey, oldest = cachedKey, item.expires
+			}
+		}
+
+		if len(p.hosts) >= pacCacheSize {
+			delete(p.hosts, oldestKey)
+		}
+	}
+
+	p.hosts[key] = pacCacheItem{proxy: proxy, expires: now.Add(p.cacheTTL)}
+}
+
+// route returns the Proxy for a PAC result string, building it on first use.
+func (p *PAC) route(result string) (Proxy, error) {
+	if value, ok := p.routes.Load(result); ok {
+		return value.(Proxy), nil
+	}
+
+	proxies, err := ParsePACResult(result, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var proxy Proxy = NewFailoverProxy(proxies...)
+	if len(proxies) == 1 {
+		proxy = proxies[0]
+	}
+
+	value, _ := p.routes.LoadOrStore(result, proxy)
+	return value.(Proxy), nil
+}
+
+// ParsePACResult maps a FindProxyForURL result onto Proxy instances in PAC order.
+//
+// Supported entries are DIRECT, PROXY and HTTP (HTTPProxy over http://), HTTPS
+// (HTTPProxy over https://), SOCKS or SOCKS4 (SOCKS4Proxy) and SOCKS5 (SOCKS5Proxy).
+// A bare SOCKS means version 4, as in the original PAC specification and in
+// browsers. Unsupported entries are skipped; an empty result means DIRECT, as
+// the PAC specification requires.
+func ParsePACResult(result string, timeout time.Duration) ([]Proxy, error) {
+	proxies := make([]Proxy, 0, 1)
+	skipped := make([]string, 0)
+
+	for _, item := range strings.Split(result, ";") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+
+		kind := strings.ToUpper(fields[0])
+		if kind == "DIRECT" {
+			direct, _ := NewDirectProxy("", timeout)
+			proxies = append(proxies, direct)
+			continue
+		}
+
+		if len(fields) != 2 {
+			skipped = append(skipped, strings.TrimSpace(item))
+			continue
+		}
+
+		var scheme string
+		switch kind {
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS", "SOCKS4":
+			scheme = "socks4"
+		case "SOCKS5":
+			scheme = "socks5"
+		default:
+			skipped = append(skipped, strings.TrimSpace(item))
+			continue
+		}
+
+		proxy, err := ParseProxyURL(scheme+"://"+fields[1], timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		proxies = append(proxies, proxy)
+	}
+
+	if len(proxies) == 0 {
+		if len(skipped) > 0 {
+			return nil, fmt.Errorf("pac result has no supported entries: %s", strings.Join(skipped, "; "))
+		}
+
+		direct, _ := NewDirectProxy("", timeout)
+		proxies = append(proxies, direct)
+	}
+
+	return proxies, nil
+}
+
+// registerPACHelpers exposes the PAC helpers that need the network stack.
+// Their DNS lookups end with the context returned by evalCtx.
+func registerPACHelpers(runtime *goja.Runtime, evalCtx func() context.Context) {
+	// resolveIPv4 returns the IPv4 address of host, or nil if it has none.
+	resolveIPv4 := func(host string) net.IP {
+		ip, _ := resolveIP(evalCtx(), "ip4", host)
+		return ip
+	}
+
+	_ = runtime.Set("dnsResolve", func(host string) goja.Value {
+		if ip := resolveIPv4(host); ip != nil {
+			return runtime.ToValue(ip.String())
+		}
+
+		return goja.Null()
+	})
+
+	_ = runtime.Set("isResolvable", func(host string) bool {
+		return resolveIPv4(host) != nil
+	})
+
+	_ = runtime.Set("myIpAddress", func() string {
+		conn, err := net.Dial("udp4", "192.0.2.1:80")
+		if err != nil {
+			return "127.0.0.1"
+		}
+		defer conn.Close()
+
+		return conn.LocalAddr().(*net.UDPAddr).IP.String()
+	})
+
+	_ = runtime.Set("isInNet", func(host, pattern, mask string) bool {
+		ip := resolveIPv4(host)
+		network := net.ParseIP(pattern).To4()
+		netmask := net.ParseIP(mask).To4()
+
+		if ip == nil || network == nil || netmask == nil {
+			return false
+		}
+
+		m := net.IPMask(netmask)
+		return ip.Mask(m).Equal(network.Mask(m))
+	})
+}
+
+// pacHelpersScript defines the PAC helpers that can be written in plain JavaScript.
+const pacHelpersScript = `
+function isPlainHostName(host) {
+	return host.indexOf('.') < 0;
+}
+
+function dnsDomainIs(host, domain) {
+	return host.length >= domain.length && host.substring(host.length - domain.length) === domain;
+}
+
+function localHostOrDomainIs(host, hostdom) {
+	return host === hostdom || hostdom.lastIndexOf(host + '.', 0) === 0;
+}
+
+function dnsDomainLevels(host) {
+	return host.split('.').length - 1;
+}
+
+function convert_addr(ipchars) {
+	var bytes = ipchars.split('.');
+	return ((bytes[0] & 0xff) << 24) | ((bytes[1] & 0xff) << 16) | ((bytes[2] & 0xff) << 8) | (bytes[3] & 0xff);
+}
+
+function shExpMatch(str, shexp) {
+	var pattern = shexp.replace(/[.+^${}()|[\]\\]/g, '\\$&').replace(/\*/g, '.*').replace(/\?/g, '.');
+	return new RegExp('^' + pattern + '$').test(str);
+}
+
+function __pacUseGMT(args) {
+	return args.length > 0 && args[args.length - 1] === 'GMT';
+}
+
+function weekdayRange() {
+	var days = ['SUN', 'MON', 'TUE', 'WED', 'THU', 'FRI', 'SAT'];
+	var gmt = __pacUseGMT(arguments);
+	var now = new Date();
+	var today = gmt ? now.getUTCDay() : now.getDay();
+	var first = days.indexOf(arguments[0]);
+	var last = (arguments.length > (gmt ? 2 : 1)) ? days.indexOf(arguments[1]) : first;
+
+	if (first < 0 || last < 0) {
+		return false;
+	}
+
+	return first <= last ? (today >= first && today <= last) : (today >= first || today <= last);
+}
+
+function timeRange() {
+	var gmt = __pacUseGMT(arguments);
+	var args = Array.prototype.slice.call(arguments, 0, gmt ? arguments.length - 1 : arguments.length);
+	var now = new Date();
+	var current = (gmt ? now.getUTCHours() : now.getHours()) * 3600 +
+		(gmt ? now.getUTCMinutes() : now.getMinutes()) * 60 +
+		(gmt ? now.getUTCSeconds() : now.getSeconds());
+	var start, end;
+
+	switch (args.length) {
+	case 1:
+		start = args[0] * 3600; end = start + 3599; break;
+	case 2:
+		start = args[0] * 3600; end = args[1] * 3600 + 3599; break;
+	case 4:
+		start = args[0] * 3600 + args[1] * 60; end = args[2] * 3600 + args[3] * 60 + 59; break;
+	case 6:
+		start = args[0] * 3600 + args[1] * 60 + args[2]; end = args[3] * 3600 + args[4] * 60 + args[5]; break;
+	default:
+		return false;
+	}
+
+	return start <= end ? (current >= start && current <= end) : (current >= start || current <= end);
+}
+
+function dateRange() {
+	var months = ['JAN', 'FEB', 'MAR', 'APR', 'MAY', 'JUN', 'JUL', 'AUG', 'SEP', 'OCT', 'NOV', 'DEC'];
+	var gmt = __pacUseGMT(arguments);
+	var args = Array.prototype.slice.call(arguments, 0, gmt ? arguments.length - 1 : arguments.length);
+	var now = new Date();
+	var today = {
+		day: gmt ? now.getUTCDate() : now.getDate(),
+		month: gmt ? now.getUTCMonth() : now.getMonth(),
+		year: gmt ? now.getUTCFullYear() : now.getFullYear()
+	};
+
+	// Each argument is a day (1-31), a month name or a four-digit year.
+	function parse(values) {
+		var date = {};
+		for (var i = 0; i < values.length; i++) {
+			var value = values[i];
+			if (typeof value === 'string' && months.indexOf(value) >= 0) {
+				date.month = months.indexOf(value);
+			} else if (value > 31) {
+				date.year = value;
+			} else {
+				date.day = value;
+			}
+		}
+		return date;
+	}
+
+	function key(date, fields) {
+		return (fields.year ? (date.year || 0) : 0) * 10000 +
+			(fields.month ? (date.month || 0) : 0) * 100 +
+			(fields.day ? (date.day || 0) : 0);
+	}
+
+	var half = args.length / 2;
+	var start = parse(args.length === 1 ? args : args.slice(0, half));
+	var end = args.length === 1 ? start : parse(args.slice(half));
+	var fields = { year: 'year' in start, month: 'month' in start, day: 'day' in start };
+
+	var from = key(start, fields), to = key(end, fields), current = key(today, fields);
+	return from <= to ? (current >= from && current <= to) : (current >= from || current <= to);
+}
+`
@@ -0,0 +1,311 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestNewPAC(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SyntaxError", func(t *testing.T) {
+		pac, err := NewPAC("function FindProxyForURL(url, host) {", 0, 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, pac)
+	})
+
+	t.Run("MissingFindProxyForURL", func(t *testing.T) {
+		pac, err := NewPAC("var x = 1;", 0, 0)
+
+		assert.ErrorIs(t, err, ErrPACInvalid)
+		assert.Nil(t, pac)
+	})
+
+	t.Run("DefaultCacheTTL", func(t *testing.T) {
+		pac, err := NewPAC("function FindProxyForURL(url, host) { return 'DIRECT'; }", time.Second, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, defaultPACCacheTTL, pac.cacheTTL)
+		assert.Equal(t, time.Second, pac.timeout)
+	})
+}
+
+func TestPACFindProxy(t *testing.T) {
+	t.Parallel()
+
+	script := `
+function FindProxyForURL(url, host) {
+	if (isPlainHostName(host) || dnsDomainIs(host, ".corp.example")) {
+		return "DIRECT";
+	}
+	if (isInNet(host, "10.0.0.0", "255.0.0.0")) {
+		return "SOCKS5 10.1.1.1:1080";
+	}
+	if (shExpMatch(url, "*://*.media.example/*")) {
+		return "PROXY media-proxy:3128; DIRECT";
+	}
+	if (localHostOrDomainIs(host, "www.example") && dnsDomainLevels(host) === 1) {
+		return "HTTPS secure-proxy:443";
+	}
+	return "PROXY proxy-a:3128; PROXY proxy-b:3128";
+}`
+
+	pac, err := NewPAC(script, time.Second, 0)
+	assert.NoError(t, err)
+
+	cases := map[string]string{
+		"http://intranet/":                "DIRECT",
+		"https://app.corp.example/":       "DIRECT",
+		"http://10.2.3.4/":                "SOCKS5 10.1.1.1:1080",
+		"https://cdn.media.example/a.mp4": "PROXY media-proxy:3128; DIRECT",
+		"https://www.example/":            "HTTPS secure-proxy:443",
+		"https://elsewhere.example.org/x": "PROXY proxy-a:3128; PROXY proxy-b:3128",
+	}
+
+	for target, expected := range cases {
+		result, err := pac.FindProxy(target)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result, target)
+	}
+}
+
+func TestPACTimeHelpers(t *testing.T) {
+	t.Parallel()
+
+	pac, err := NewPAC(`
+function FindProxyForURL(url, host) {
+	var results = [
+		weekdayRange("SUN", "SAT"),
+		timeRange(0, 23, "GMT"),
+		dateRange("JAN", "DEC"),
+		dateRange(1, 31),
+		weekdayRange("XYZ")
+	];
+	return results.join(",");
+}`, 0, 0)
+	assert.NoError(t, err)
+
+	result, err := pac.FindProxy("http://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, "true,true,true,true,false", result)
+}
+
+func TestPACEvaluationTimeout(t *testing.T) {
+	t.Parallel()
+
+	pac, err := NewPAC("function FindProxyForURL(url, host) { while (true) {} }", 0, 0)
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = pac.FindProxy("http://example.com/")
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 3*pacEvalTimeout)
+}
+
+func TestPACDNSHelpersBoundedByEvaluation(t *testing.T) {
+	t.Parallel()
+
+	pac, err := NewPAC(`
+function FindProxyForURL(url, host) {
+	return remaining() + "," + dnsResolve("127.0.0.1");
+}`, 0, 0)
+	assert.NoError(t, err)
+
+	_ = pac.runtime.Set("remaining", func() int64 {
+		deadline, ok := pac.evalContext().Deadline()
+		if !ok {
+			return -1
+		}
+
+		return int64(time.Until(deadline))
+	})
+
+	result, err := pac.FindProxy("http://example.com/")
+	assert.NoError(t, err)
+
+	remaining, resolved, _ := strings.Cut(result, ",")
+	assert.Equal(t, "127.0.0.1", resolved)
+
+	left, err := strconv.ParseInt(remaining, 10, 64)
+	assert.NoError(t, err)
+	assert.Greater(t, left, int64(0))
+	assert.LessOrEqual(t, left, int64(pacEvalTimeout))
+
+	_, ok := pac.evalContext().Deadline()
+	assert.False(t, ok, "the context ends with the evaluation")
+}
+
+func TestParsePACResult(t *testing.T) {
+	t.Parallel()
+
+	t.Run("MapsEntriesInOrder", func(t *testing.T) {
+		proxies, err := ParsePACResult("PROXY a:3128; HTTPS b:443; SOCKS c:1080; SOCKS5 d:1080; DIRECT", time.Second)
+
+		assert.NoError(t, err)
+		assert.Len(t, proxies, 5)
+		assert.Equal(t, "http://a:3128", proxies[0].(*HTTPProxy).url)
+		assert.Equal(t, "https://b:443", proxies[1].(*HTTPProxy).url)
+		assert.Equal(t, "c:1080", proxies[2].(*SOCKS4Proxy).addr)
+		assert.Equal(t, "socks5://d:1080", proxies[3].(*SOCKS5Proxy).url)
+		assert.IsType(t, &DirectProxy{}, proxies[4])
+	})
+
+	t.Run("SOCKS4", func(t *testing.T) {
+		proxies, err := ParsePACResult("SOCKS4 a:1080", 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "a:1080", proxies[0].(*SOCKS4Proxy).addr)
+	})
+
+	t.Run("BareSOCKSIsVersion4", func(t *testing.T) {
+		proxies, err := ParsePACResult("SOCKS a:1080; socks5 b:1080", 0)
+
+		assert.NoError(t, err)
+		assert.Len(t, proxies, 2)
+		assert.Equal(t, "a:1080", proxies[0].(*SOCKS4Proxy).addr)
+		assert.Equal(t, "socks5://b:1080", proxies[1].(*SOCKS5Proxy).url)
+	})
+
+	t.Run("EmptyResultIsDirect", func(t *testing.T) {
+		proxies, err := ParsePACResult("", 0)
+
+		assert.NoError(t, err)
+		assert.Len(t, proxies, 1)
+		assert.IsType(t, &DirectProxy{}, proxies[0])
+	})
+
+	t.Run("SkipsUnsupportedEntries", func(t *testing.T) {
+		proxies, err := ParsePACResult("QUIC a:443; PROXY b:3128", 0)
+
+		assert.NoError(t, err)
+		assert.Len(t, proxies, 1)
+	})
+
+	t.Run("OnlyUnsupportedEntries", func(t *testing.T) {
+		proxies, err := ParsePACResult("QUIC a:443", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxies)
+	})
+}
+
+func TestPACProxyFor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("CachesPerHostAndReusesRoutes", func(t *testing.T) {
+		pac, err := NewPAC(`
+var calls = 0;
+function FindProxyForURL(url, host) {
+	calls++;
+	return "PROXY proxy-a:3128; DIRECT";
+}`, 0, time.Minute)
+		assert.NoError(t, err)
+
+		first, err := pac.ProxyFor("https://one.example/a")
+		assert.NoError(t, err)
+		assert.Len(t, first.(*FailoverProxy).Proxies(), 2)
+
+		second, err := pac.ProxyFor("https://one.example/b")
+		assert.NoError(t, err)
+		assert.Same(t, first, second)
+
+		third, err := pac.ProxyFor("https://two.example/")
+		assert.NoError(t, err)
+		assert.Same(t, first, third, "identical PAC results share a Proxy")
+
+		assert.Equal(t, int64(2), pac.runtime.Get("calls").ToInteger())
+	})
+
+	t.Run("CachesPerScheme", func(t *testing.T) {
+		pac, err := NewPAC(`
+function FindProxyForURL(url, host) {
+	return url.substring(0, 6) === "https:" ? "PROXY proxy-a:3128" : "DIRECT";
+}`, 0, time.Minute)
+		assert.NoError(t, err)
+
+		secure, err := pac.ProxyFor("https://one.example/")
+		assert.NoError(t, err)
+		assert.IsType(t, &HTTPProxy{}, secure)
+
+		plain, err := pac.ProxyFor("http://one.example/")
+		assert.NoError(t, err)
+		assert.IsType(t, &DirectProxy{}, plain)
+	})
+
+	t.Run("CacheIsBounded", func(t *testing.T) {
+		pac, err := NewPAC(`function FindProxyForURL(url, host) { return "DIRECT"; }`, 0, time.Minute)
+		assert.NoError(t, err)
+
+		direct, _ := NewDirectProxy("", 0)
+		now := time.Now()
+
+		// Half of the cache expires, the other half stays valid.
+		for i := range pacCacheSize {
+			stored := now
+			if i%2 == 0 {
+				stored = now.Add(-2 * time.Minute)
+			}
+
+			pac.store(pacCacheKey{scheme: "http", host: strconv.Itoa(i)}, direct, stored)
+		}
+
+		pac.store(pacCacheKey{scheme: "http", host: "new"}, direct, now)
+		assert.Len(t, pac.hosts, pacCacheSize/2+1, "expired decisions are dropped")
+
+		for i := range pacCacheSize {
+			pac.store(pacCacheKey{scheme: "https", host: strconv.Itoa(i)}, direct, now.Add(time.Duration(i+1)))
+		}
+
+		assert.Len(t, pac.hosts, pacCacheSize)
+		assert.Nil(t, pac.cached(pacCacheKey{scheme: "http", host: "1"}, now), "the decision closest to expiry is evicted")
+		assert.NotNil(t, pac.cached(pacCacheKey{scheme: "https", host: "0"}, now))
+	})
+
+	t.Run("NegativeTTLDisablesCache", func(t *testing.T) {
+		pac, err := NewPAC(`
+var calls = 0;
+function FindProxyForURL(url, host) { calls++; return "DIRECT"; }`, 0, -1)
+		assert.NoError(t, err)
+
+		_, _ = pac.ProxyFor("https://one.example/")
+		_, _ = pac.ProxyFor("https://one.example/")
+
+		assert.Equal(t, int64(2), pac.runtime.Get("calls").ToInteger())
+	})
+}
+
+func TestClientWithPAC(t *testing.T) {
+	t.Parallel()
+
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+
+	proxyServer, tunnels := newConnectProxyServer(t)
+	defer proxyServer.Close()
+
+	// The first proxy refuses connections, so PAC failover moves on to the second one.
+	pac, err := NewPAC(`
+function FindProxyForURL(url, host) {
+	return "PROXY 127.0.0.1:1; PROXY `+proxyServer.Listener.Addr().String()+`";
+}`, time.Second, 0)
+	assert.NoError(t, err)
+
+	pool := NewPool([]Proxy{&mockProxy{id: 1}}, PoolConfig{})
+	client, err := NewClient(ClientConfig{PAC: pac, Pool: pool})
+	assert.NoError(t, err)
+
+	res := doRequest(t, client, targetServer.URL)
+	assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+	assert.Equal(t, int64(1), tunnels.Load())
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPeakEWMADecay         = 10 * time.Second
+	defaultPeakEWMAProbeInterval = 30 * time.Second
+
+	// defaultPeakEWMARTT is the latency assumed for an entry that has no samples yet.
+	defaultPeakEWMARTT = 30 * time.Millisecond
+)
+
+// PeakEWMASelector picks the entry with the lowest expected cost, where cost is an
+// exponentially weighted moving average of latency multiplied by the number of
+// requests currently in flight through the entry.
+//
+// The average is "peak-sensitive": a sample slower than the current estimate replaces
+// it immediately, while faster samples only pull it down gradually. This makes the
+// selector react to a degrading proxy within one request, unlike the lifetime average
+// used by WeightedSelector. The approach follows Finagle's and Linkerd's Peak EWMA
+// load balancers and, like them, compares two random candidates (power of two choices)
+// rather than scanning for the global minimum, which avoids herding onto one proxy.
+//
+// Entries that have not been picked for longer than the probe interval are handed
+// out once regardless of cost, so the estimate of a proxy that lost traffic after
+// a slow period can recover instead of going stale.
+//
+// In-flight requests are estimated from the picks handed out minus the results
+// recorded in Stats, so callers only need to keep reporting results through
+// RecordSuccess/RecordFailed and RecordLatency. The estimate decays with the same
+// time constant as the latency average, so picks that never get a result, such
+// as cancelled requests, stop counting.
+type PeakEWMASelector struct {
+	decay         time.Duration
+	probeInterval time.Duration
+	randIntN      func(n int) int
+
+	// states maps *Entry to its *peakEWMAState.
+	states sync.Map
+}
+
+type peakEWMAState struct {
+	mutex sync.Mutex
+
+	// cost is the current latency estimate in nanoseconds; zero until the first sample.
+	cost float64
+
+	// stamp is the time of the last sample folded into cost.
+	stamp time.Time
+
+	// cursor is the position in the Stats latency ring consumed so far.
+	cursor uint64
+
+	inflight inFlight
+
+	lastPicked time.Time
+}
+
+// NewPeakEWMASelector initializes a new PeakEWMASelector.
+//
+// decay is the time constant of the moving average: a sample's influence drops
+// to 1/e after that long. probeInterval is how long an entry may go unpicked
+// before it is probed; a negative value disables probing. Zero values fall back
+// to 10s and 30s respectively.
+func NewPeakEWMASelector(decay, probeInterval time.Duration) *PeakEWMASelector {
+	if decay <= 0 {
+		decay = defaultPeakEWMADecay
+	}
+
+	if probeInterval == 0 {
+		probeInterval = defaultPeakEWMAProbeInterval
+	}
+
+	return &PeakEWMASelector{decay: decay, probeInterval: probeInterval, randIntN: rand.IntN}
+}
+
+// Select returns a stale entry due for probing if there is one,
+// otherwise the cheaper of two randomly chosen entries.
+func (p *PeakEWMASelector) Select(entries []*Entry) *Entry {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	if p.probeInterval > 0 {
+		for _, entry := range entries {
+			if p.state(entry).claimProbe(now, p.probeInterval) {
+				return entry
+			}
+		}
+	}
+
+	chosen := entries[0]
+	if len(entries) > 1 {
+		first := p.randIntN(len(entries))
+		second := p.randIntN(len(entries) - 1)
+		if second >= first {
+			second++
+		}
+
+		chosen = entries[first]
+		if p.cost(entries[second]) < p.cost(chosen) {
+			chosen = entries[second]
+		}
+	}
+
+	p.state(chosen).pick(now)
+	return chosen
+}
+
+func (p *PeakEWMASelector) forget(entry *Entry) {
+	p.states.Delete(entry)
+}
+
+func (p *PeakEWMASelector) state(entry *Entry) *peakEWMAState {
+	if value, ok := p.states.Load(entry); ok {
+		return value.(*peakEWMAState)
+	}
+
+	value, _ := p.states.LoadOrStore(entry, &peakEWMAState{inflight: newInFlight(entry, p.decay)})
+	return value.(*peakEWMAState)
+}
+
+// cost folds any new latency samples into the entry's estimate
+// and returns estimate * (pending + 1) / slow-start factor.
+func (p *PeakEWMASelector) cost(entry *Entry) float64 {
+	state := p.state(entry)
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	samples, cursor := entry.stats.latencySamplesAfter(state.cursor)
+	state.cursor = cursor
+
+	for _, sample := range samples {
+		rtt := float64(sample.latency)
+
+		if rtt > state.cost {
+			state.cost = rtt
+		} else {
+			elapsed := max(float64(sample.at.Sub(state.stamp)), 0)
+			weight := math.Exp(-elapsed / float64(p.decay))
+			state.cost = state.cost*weight + rtt*(1-weight)
+		}
+
+		state.stamp = sample.at
+	}
+
+	estimate := state.cost
+	if estimate == 0 {
+		estimate = float64(defaultPeakEWMARTT)
+	}
+
+	pending := state.inflight.count(entry, time.Now())
+
+	// Entries in slow start look proportionally more expensive.
+	return estimate * (pending + 1) / entry.SlowStartFactor()
+}
+
+func (s *peakEWMAState) pick(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.inflight.add(now)
+	s.lastPicked = now
+}
+
+// claimProbe records a pick and returns true if the entry has not been
+// picked for at least interval. Only one concurrent caller can claim it.
+func (s *peakEWMAState) claimProbe(now time.Time, interval time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now.Sub(s.lastPicked) < interval {
+		return false
+	}
+
+	s.inflight.add(now)
+	s.lastPicked = now
+	return true
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeakEWMA(t *testing.T) {
+	t.Parallel()
+
+	t.Run("EmptyEntries", func(t *testing.T) {
+		selector := NewPeakEWMASelector(0, 0)
+		assert.NotNil(t, selector)
+
+		assert.Nil(t, selector.Select(nil))
+	})
+
+	t.Run("InitSelectorWithDefaultValues", func(t *testing.T) {
+		selector := NewPeakEWMASelector(0, 0)
+
+		assert.Equal(t, defaultPeakEWMADecay, selector.decay)
+		assert.Equal(t, defaultPeakEWMAProbeInterval, selector.probeInterval)
+		assert.NotNil(t, selector.randIntN)
+	})
+
+	t.Run("PrefersLowerLatency", func(t *testing.T) {
+		selector := NewPeakEWMASelector(time.Second, -1)
+
+		fast := newEntry(&mockProxy{id: 1})
+		fast.stats.RecordLatency(10 * time.Millisecond)
+
+		slow := newEntry(&mockProxy{id: 2})
+		slow.stats.RecordLatency(100 * time.Millisecond)
+
+		entries := []*Entry{fast, slow}
+		for i := 0; i < 50; i++ {
+			selected := selector.Select(entries)
+			assert.Equal(t, fast, selected)
+
+			selected.stats.RecordSuccess()
+			selected.stats.RecordLatency(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("PeakSampleReplacesEstimate", func(t *testing.T) {
+		selector := NewPeakEWMASelector(time.Hour, -1)
+		entry := newEntry(&mockProxy{id: 1})
+
+		entry.stats.RecordLatency(10 * time.Millisecond)
+		assert.Equal(t, float64(10*time.Millisecond), selector.cost(entry))
+
+		entry.stats.RecordLatency(200 * time.Millisecond)
+		assert.Equal(t, float64(200*time.Millisecond), selector.cost(entry))
+
+		// A fast sample shortly after only pulls the estimate down a little.
+		entry.stats.RecordLatency(10 * time.Millisecond)
+		assert.InDelta(t, float64(200*time.Millisecond), selector.cost(entry), float64(time.Millisecond))
+	})
+
+	t.Run("PenalizesInFlightRequests", func(t *testing.T) {
+		selector := NewPeakEWMASelector(time.Second, -1)
+
+		first := newEntry(&mockProxy{id: 1})
+		second := newEntry(&mockProxy{id: 2})
+		first.stats.RecordLatency(10 * time.Millisecond)
+		second.stats.RecordLatency(15 * time.Millisecond)
+
+		entries := []*Entry{first, second}
+		assert.Equal(t, first, selector.Select(entries))
+
+		// The first request has not completed yet: 10ms * 2 > 15ms * 1.
+		assert.Equal(t, second, selector.Select(entries))
+
+		first.stats.RecordSuccess()
+		second.stats.RecordSuccess()
+		assert.Equal(t, first, selector.Select(entries))
+	})
+
+	t.Run("UnfinishedPicksDecay", func(t *testing.T) {
+		selector := NewPeakEWMASelector(10*time.Millisecond, -1)
+
+		entry := newEntry(&mockProxy{id: 1})
+		entry.stats.RecordLatency(10 * time.Millisecond)
+
+		// Picks whose requests were cancelled never record a result.
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, entry, selector.Select([]*Entry{entry}))
+		}
+
+		assert.Greater(t, selector.cost(entry), float64(50*time.Millisecond))
+
+		time.Sleep(100 * time.Millisecond)
+		assert.InDelta(t, float64(10*time.Millisecond), selector.cost(entry), float64(time.Millisecond))
+	})
+
+	t.Run("ProbesStaleEntries", func(t *testing.T) {
+		selector := NewPeakEWMASelector(time.Second, 20*time.Millisecond)
+
+		fast := newEntry(&mockProxy{id: 1})
+		fast.stats.RecordLatency(10 * time.Millisecond)
+
+		slow := newEntry(&mockProxy{id: 2})
+		slow.stats.RecordLatency(time.Second)
+
+		entries := []*Entry{fast, slow}
+
+		// Both entries are new, so each is probed once first.
+		assert.Equal(t, fast, selector.Select(entries))
+		assert.Equal(t, slow, selector.Select(entries))
+
+		slow.stats.RecordSuccess()
+		for i := 0; i < 5; i++ {
+			selected := selector.Select(entries)
+			assert.Equal(t, fast, selected)
+			selected.stats.RecordSuccess()
+		}
+
+		time.Sleep(25 * time.Millisecond)
+
+		probed := 0
+		for i := 0; i < 3; i++ {
+			if selector.Select(entries) == slow {
+				probed++
+			}
+		}
+
+		assert.Equal(t, 1, probed)
+	})
+	t.Run("SlowStartRaisesCost", func(t *testing.T) {
+		selector := NewPeakEWMASelector(time.Second, -1)
+
+		entry := newEntry(&mockProxy{id: 1})
+		entry.stats.RecordLatency(10 * time.Millisecond)
+		assert.Equal(t, float64(10*time.Millisecond), selector.cost(entry))
+
+		entry.slowStart = time.Hour
+		entry.warmingSinceUnix.Store(time.Now().UnixNano())
+		assert.InDelta(t, float64(100*time.Millisecond), selector.cost(entry), float64(time.Millisecond))
+	})
+}
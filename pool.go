@@ -1,6 +1,7 @@
 package client
 
 import (
+	"slices"
 	"sync"
 	"time"
 )
@@ -9,13 +10,53 @@ import (
 type PoolConfig struct {
 	// MaxFails is the number of consecutive failures after which
 	// a proxy is placed in quarantine and skipped by Pick.
-	// Defaults to 3 if zero.
+	// Only used by the default HealthPolicy. Defaults to 3 if zero.
 	MaxFails int64
 
+	// HealthPolicy decides when a proxy is placed in quarantine.
+	// Defaults to ConsecutiveFailsPolicy with MaxFails if nil.
+	HealthPolicy HealthPolicy
+
 	// CooldownWindow is the duration a proxy stays in quarantine before
 	// being given another chance. Defaults to 30s if zero.
 	CooldownWindow time.Duration
 
+	// HalfOpenMaxRequests is the number of trial requests let through once
+	// a quarantined proxy's cooldown has elapsed. Defaults to 1 if zero.
+	HalfOpenMaxRequests int64
+
+	// HalfOpenSuccessRatio is the fraction of trial requests that must succeed
+	// for the circuit to close again. The circuit reopens as soon as this ratio
+	// can no longer be reached. Defaults to 1.0 if zero.
+	HalfOpenSuccessRatio float64
+
+	// OnStateChange, when set, is called on every circuit breaker transition
+	// of an entry. It runs synchronously inside Pick, without the pool lock
+	// held, so it may call Add or Remove; it must not block.
+	OnStateChange func(entry *Entry, from, to CircuitState)
+
+	// CooldownBackoff, when set, replaces the fixed CooldownWindow with an
+	// escalating one: every repeated quarantine of the same proxy asks the
+	// strategy for the next delay, so a permanently dead proxy is retried
+	// less and less often. Defaults to nil (fixed CooldownWindow).
+	CooldownBackoff Backoff
+
+	// CooldownResetSuccesses is the number of consecutive successes after which
+	// a proxy's quarantine escalation is reset. Only used with CooldownBackoff.
+	// Defaults to 5 if zero.
+	CooldownResetSuccesses int64
+
+	// StatsWindow is the length of the recent window each entry's Stats
+	// aggregate over, in addition to lifetime counters. Defaults to 1m if zero.
+	StatsWindow time.Duration
+
+	// SlowStart is the window during which a proxy added to a running pool
+	// or recovered from quarantine ramps up linearly from a small fraction
+	// of its weight to full weight, instead of receiving a full share of traffic
+	// immediately. Honoured by WeightedSelector, SmoothWeightedSelector and
+	// PeakEWMASelector. Defaults to 0 (disabled).
+	SlowStart time.Duration
+
 	// Selector determines which healthy proxy Pick should hand out.
 	// Defaults to RoundRobinSelector if nil.
 	Selector Selector
@@ -23,9 +64,13 @@ type PoolConfig struct {
 
 func defaultPoolConfig() PoolConfig {
 	return PoolConfig{
-		MaxFails:       3,
-		CooldownWindow: 30 * time.Second,
-		Selector:       &RoundRobinSelector{},
+		MaxFails:               3,
+		CooldownWindow:         30 * time.Second,
+		CooldownResetSuccesses: 5,
+		HalfOpenMaxRequests:    1,
+		HalfOpenSuccessRatio:   1.0,
+		StatsWindow:            defaultStatsWindow,
+		Selector:               &RoundRobinSelector{},
 	}
 }
 
@@ -48,48 +93,193 @@ func NewPool(proxies []Proxy, cfg PoolConfig) *Pool {
 		cfg.CooldownWindow = defaultCfg.CooldownWindow
 	}
 
+	if cfg.HealthPolicy == nil {
+		cfg.HealthPolicy = NewConsecutiveFailsPolicy(cfg.MaxFails)
+	}
+
+	if cfg.CooldownResetSuccesses == 0 {
+		cfg.CooldownResetSuccesses = defaultCfg.CooldownResetSuccesses
+	}
+
+	if cfg.HalfOpenMaxRequests == 0 {
+		cfg.HalfOpenMaxRequests = defaultCfg.HalfOpenMaxRequests
+	}
+
+	if cfg.HalfOpenSuccessRatio == 0 {
+		cfg.HalfOpenSuccessRatio = defaultCfg.HalfOpenSuccessRatio
+	}
+
+	if cfg.StatsWindow == 0 {
+		cfg.StatsWindow = defaultCfg.StatsWindow
+	}
+
 	if cfg.Selector == nil {
 		cfg.Selector = defaultCfg.Selector
 	}
 
 	entries := make([]*Entry, 0, len(proxies))
+	pool := &Pool{cfg: cfg}
+	for _, proxy := range proxies {
+		entries = append(entries, pool.newEntry(proxy))
+	}
+
+	pool.entries = entries
+	return pool
+}
+
+// Add appends proxies to a running pool and returns their entries.
+// New entries go through the configured SlowStart window.
+func (p *Pool) Add(proxies ...Proxy) []*Entry {
+	added := make([]*Entry, 0, len(proxies))
+	now := time.Now().UnixNano()
+
 	for _, proxy := range proxies {
-		entries = append(entries, newEntry(proxy))
+		entry := p.newEntry(proxy)
+		entry.warmingSinceUnix.Store(now)
+		added = append(added, entry)
 	}
 
-	return &Pool{entries: entries, cfg: cfg}
+	p.mutex.Lock()
+	p.entries = append(slices.Clip(p.entries), added...)
+	p.mutex.Unlock()
+
+	return added
+}
+
+// entryForgetter is implemented by selectors and health policies that keep
+// per-entry state, so that Remove can release it.
+type entryForgetter interface {
+	forget(entry *Entry)
+}
+
+// forgetEntry releases the state component keeps for entry, if any.
+func forgetEntry(component any, entry *Entry) {
+	if forgetter, ok := component.(entryForgetter); ok {
+		forgetter.forget(entry)
+	}
+}
+
+// Remove takes entries out of a running pool and returns how many were found.
+//
+// Removed entries are no longer picked and report Removed; a Client closes the
+// keep-alive connections it holds through them. Requests already in flight
+// through a removed entry complete normally.
+func (p *Pool) Remove(entries ...*Entry) int {
+	var removed []*Entry
+
+	p.mutex.Lock()
+	p.entries = slices.DeleteFunc(slices.Clone(p.entries), func(e *Entry) bool {
+		if slices.Contains(entries, e) {
+			removed = append(removed, e)
+			return true
+		}
+
+		return false
+	})
+	p.mutex.Unlock()
+
+	for _, entry := range removed {
+		entry.removed.Store(true)
+		forgetEntry(p.cfg.Selector, entry)
+		forgetEntry(p.cfg.HealthPolicy, entry)
+	}
+
+	return len(removed)
+}
+
+func (p *Pool) newEntry(proxy Proxy) *Entry {
+	entry := newEntry(proxy)
+	entry.stats.recent.setWindow(p.cfg.StatsWindow)
+	entry.slowStart = p.cfg.SlowStart
+
+	return entry
+}
+
+// Entries returns a snapshot of all entries in the pool, healthy or not,
+// in the order the proxies were provided.
+func (p *Pool) Entries() []*Entry {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return slices.Clone(p.entries)
 }
 
 // Pick selects the next proxy to use according to the configured Selector.
 //
-// Only healthy entries (those that pass HealthCheck) are offered to the Selector.
+// Only available entries are offered to the Selector: those whose circuit is
+// closed, or half-open with trial permits left. A half-open entry consumes a
+// permit when picked; if another caller took the last one first, the entry is
+// dropped from the candidates and selection is repeated.
 // If every proxy is currently in quarantine, Pick falls back to selecting from
 // the full list rather than returning an error — this prevents a total stall
 // when all proxies are temporarily degraded.
 func (p *Pool) Pick() (*Entry, error) {
-	p.mutex.RLock()
-	healthy := p.healthyEntries()
-	all := p.entries
-	p.mutex.RUnlock()
-
-	entriesList := healthy
-	if len(entriesList) == 0 {
-		if len(all) == 0 {
-			return nil, ErrProxyPoolEmpty
+	all := p.snapshot()
+	if len(all) == 0 {
+		return nil, ErrProxyPoolEmpty
+	}
+
+	if entry := p.selectHealthy(p.availableEntries(all)); entry != nil {
+		return entry, nil
+	}
+
+	return p.cfg.Selector.Select(all), nil
+}
+
+// selectHealthy picks from healthy entries, taking a trial permit for half-open ones.
+// Returns nil if no candidate could be acquired. candidates is left unchanged,
+// so callers may offer the same slice again.
+func (p *Pool) selectHealthy(candidates []*Entry) *Entry {
+	for len(candidates) > 0 {
+		entry := p.cfg.Selector.Select(candidates)
+		if entry == nil {
+			return nil
+		}
+
+		if entry.breaker.acquire(p.cfg.HalfOpenMaxRequests) {
+			return entry
+		}
+
+		remaining := make([]*Entry, 0, len(candidates)-1)
+		for _, candidate := range candidates {
+			if candidate != entry {
+				remaining = append(remaining, candidate)
+			}
 		}
 
-		entriesList = all
+		candidates = remaining
 	}
 
-	entry := p.cfg.Selector.Select(entriesList)
-	return entry, nil
+	return nil
+}
+
+// snapshotHealthy returns the currently available entries and the pool size.
+func (p *Pool) snapshotHealthy() ([]*Entry, int) {
+	entries := p.snapshot()
+	return p.availableEntries(entries), len(entries)
 }
 
 func (p *Pool) healthyEntries() []*Entry {
+	return p.availableEntries(p.snapshot())
+}
+
+// snapshot returns the current entries. Add and Remove never modify the slice
+// in place, so it stays valid after the lock is released.
+func (p *Pool) snapshot() []*Entry {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.entries
+}
+
+// availableEntries advances the circuit breakers of entries and returns those
+// that may be offered to the Selector. It must be called without p.mutex held:
+// OnStateChange callbacks run from here and may call Add or Remove.
+func (p *Pool) availableEntries(entries []*Entry) []*Entry {
 	healthyProxies := make([]*Entry, 0)
 
-	for _, entry := range p.entries {
-		if entry.HealthCheck(p.cfg.MaxFails, p.cfg.CooldownWindow) {
+	for _, entry := range entries {
+		if entry.available(&p.cfg) {
 			healthyProxies = append(healthyProxies, entry)
 		}
 	}
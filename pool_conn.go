@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DialContext picks an entry and connects to addr through its proxy, for
+// protocols other than HTTP such as websockets, database drivers or gRPC. Its
+// method value fits wherever a func(ctx, network, addr) (net.Conn, error) is
+// expected:
+//
+//	dialer := websocket.Dialer{NetDialContext: pool.DialContext}
+//
+// network and addr are checked before an entry is picked, so that a bad
+// argument neither counts against a proxy nor takes a half-open trial permit.
+// A failed dial is recorded as a failure of the entry, unless ctx ended. The
+// returned connection counts the bytes it reads and writes and records them in
+// the entry's Stats when it is closed, along with the outcome: a failure if a read
+// or write failed with anything but io.EOF or a deadline set by the caller,
+// otherwise a success with the dial time as latency. Until then, a half-open
+// entry keeps the trial permit taken by Pick.
+func (p *Pool) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	if err := checkAddr(addr); err != nil {
+		return nil, err
+	}
+
+	entry, err := p.Pick()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	conn, err := AsContextDialer(entry.Proxy()).DialContext(ctx, network, addr)
+	if err != nil {
+		if ctx.Err() == nil {
+			entry.Stats().RecordFailed()
+		}
+
+		return nil, err
+	}
+
+	return &poolConn{Conn: conn, entry: entry, dialLatency: time.Since(start)}, nil
+}
+
+// EntryOf returns the pool entry conn was dialed through,
+// or nil if conn does not come from Pool.DialContext.
+func EntryOf(conn net.Conn) *Entry {
+	if pooled, ok := conn.(interface{ Entry() *Entry }); ok {
+		return pooled.Entry()
+	}
+
+	return nil
+}
+
+// poolConn is a connection dialed through a pool entry, recording its traffic
+// and outcome in the entry's Stats when closed.
+type poolConn struct {
+	net.Conn
+	entry       *Entry
+	dialLatency time.Duration
+
+	read    atomic.Int64
+	written atomic.Int64
+	failed  atomic.Bool
+
+	recordOnce sync.Once
+}
+
+func (c *poolConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	c.observe(err)
+
+	return n, err
+}
+
+func (c *poolConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	c.observe(err)
+
+	return n, err
+}
+
+// observe marks the connection as failed on errors that point at the proxy
+// or the network rather than at the peer or the caller.
+func (c *poolConn) observe(err error) {
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, net.ErrClosed) {
+		return
+	}
+
+	c.failed.Store(true)
+}
+
+// Close closes the connection and, the first time only, records it in Stats.
+func (c *poolConn) Close() error {
+	err := c.Conn.Close()
+	c.recordOnce.Do(c.record)
+
+	return err
+}
+
+func (c *poolConn) record() {
+	stats := c.entry.Stats()
+	stats.RecordBytes(c.read.Load(), c.written.Load())
+
+	if c.failed.Load() {
+		stats.RecordFailed()
+		return
+	}
+
+	stats.RecordSuccess()
+	stats.RecordLatency(c.dialLatency)
+}
+
+// Entry returns the pool entry the connection was dialed through.
+func (c *poolConn) Entry() *Entry {
+	return c.entry
+}
+
+// ConnectResponse returns the CONNECT response of the underlying tunnel, if any.
+func (c *poolConn) ConnectResponse() *ConnectResponse {
+	return ConnectResponseOf(c.Conn)
+}
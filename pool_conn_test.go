@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newEchoServer starts a TCP server that echoes back everything it receives.
+func newEchoServer(t *testing.T) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return listener
+}
+
+func TestPoolDialContext(t *testing.T) {
+	t.Parallel()
+
+	echo := newEchoServer(t)
+	defer echo.Close()
+
+	newDirectPool := func(t *testing.T) *Pool {
+		direct, err := NewDirectProxy("", time.Second)
+		assert.NoError(t, err)
+
+		return NewPool([]Proxy{direct}, PoolConfig{})
+	}
+
+	t.Run("RecordsTrafficAndSuccessOnClose", func(t *testing.T) {
+		pool := newDirectPool(t)
+
+		conn, err := pool.DialContext(context.Background(), "tcp", echo.Addr().String())
+		assert.NoError(t, err)
+
+		_, err = conn.Write([]byte("hello"))
+		assert.NoError(t, err)
+
+		buf := make([]byte, 5)
+		_, err = io.ReadFull(conn, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(buf))
+
+		stats := pool.Entries()[0].Stats()
+		assert.Equal(t, pool.Entries()[0], EntryOf(conn))
+		assert.Equal(t, int64(0), stats.SuccessCount(), "nothing is recorded before close")
+
+		assert.NoError(t, conn.Close())
+		assert.Error(t, conn.Close())
+		assert.Equal(t, int64(1), stats.SuccessCount(), "a second close is not recorded")
+
+		assert.Equal(t, int64(5), stats.BytesRead())
+		assert.Equal(t, int64(5), stats.BytesWritten())
+		assert.Equal(t, int64(0), stats.Failures())
+		assert.Greater(t, stats.AvgLatencyMs(), 0.0)
+	})
+
+	t.Run("ThroughHTTPProxy", func(t *testing.T) {
+		proxyServer, tunnels := newConnectProxyServer(t)
+		defer proxyServer.Close()
+
+		proxy, err := NewHTTPProxy(proxyServer.URL, time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{proxy}, PoolConfig{})
+
+		conn, err := pool.DialContext(context.Background(), "tcp", echo.Addr().String())
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		assert.Equal(t, int64(1), tunnels.Load())
+		assert.Equal(t, 200, ConnectResponseOf(conn).StatusCode)
+	})
+
+	t.Run("DeadlineIsNotFailure", func(t *testing.T) {
+		pool := newDirectPool(t)
+
+		conn, err := pool.DialContext(context.Background(), "tcp", echo.Addr().String())
+		assert.NoError(t, err)
+
+		assert.NoError(t, conn.SetReadDeadline(time.Now().Add(-time.Second)))
+		_, err = conn.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+		assert.NoError(t, conn.Close())
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().SuccessCount())
+	})
+
+	t.Run("ResetRecordedAsFailure", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			// Closing with unread data and no linger makes the peer see a reset.
+			_ = conn.(*net.TCPConn).SetLinger(0)
+			_, _ = conn.Read(make([]byte, 1))
+			_ = conn.Close()
+		}()
+
+		pool := newDirectPool(t)
+
+		conn, err := pool.DialContext(context.Background(), "tcp", listener.Addr().String())
+		assert.NoError(t, err)
+
+		_, err = conn.Write([]byte("hello"))
+		assert.NoError(t, err)
+
+		_, err = conn.Read(make([]byte, 1))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, io.EOF)
+
+		assert.NoError(t, conn.Close())
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().Failures())
+		assert.Equal(t, int64(5), pool.Entries()[0].Stats().BytesWritten())
+	})
+
+	t.Run("DialFailureRecorded", func(t *testing.T) {
+		dead, err := NewHTTPProxy("http://127.0.0.1:1", time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{dead}, PoolConfig{})
+
+		conn, err := pool.DialContext(context.Background(), "tcp", echo.Addr().String())
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().Failures())
+	})
+
+	t.Run("CancelledDialNotRecorded", func(t *testing.T) {
+		listener, accepted := newHungProxyListener(t)
+		defer listener.Close()
+
+		hung, err := NewHTTPProxy("http://"+listener.Addr().String(), 0)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{hung}, PoolConfig{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err = pool.DialContext(ctx, "tcp", echo.Addr().String())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int64(0), pool.Entries()[0].Stats().Failures())
+		assertClosedByPeer(t, <-accepted)
+	})
+
+	t.Run("InvalidArgumentsNotRecorded", func(t *testing.T) {
+		dead, err := NewHTTPProxy("http://127.0.0.1:1", time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{dead}, PoolConfig{})
+
+		for _, args := range [][2]string{{"udp", echo.Addr().String()}, {"tcp", "no-port"}, {"tcp", "host:port"}} {
+			conn, err := pool.DialContext(context.Background(), args[0], args[1])
+			assert.Error(t, err, args)
+			assert.Nil(t, conn)
+		}
+
+		assert.Equal(t, int64(0), pool.Entries()[0].Stats().Failures())
+	})
+
+	t.Run("EmptyPool", func(t *testing.T) {
+		pool := NewPool(nil, PoolConfig{})
+
+		_, err := pool.DialContext(context.Background(), "tcp", echo.Addr().String())
+		assert.ErrorIs(t, err, ErrProxyPoolEmpty)
+	})
+
+	t.Run("NotFromPool", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		assert.Nil(t, EntryOf(client))
+	})
+}
@@ -19,6 +19,28 @@ func TestProxyPool(t *testing.T) {
 		assert.Equal(t, int64(3), pool.cfg.MaxFails)
 		assert.Equal(t, 30*time.Second, pool.cfg.CooldownWindow)
 		assert.Equal(t, selector, pool.cfg.Selector)
+		assert.Equal(t, NewConsecutiveFailsPolicy(3), pool.cfg.HealthPolicy)
+		assert.Equal(t, int64(time.Minute/statsBuckets), pool.entries[0].stats.recent.bucketWidth())
+	})
+
+	t.Run("PoolWithCustomHealthPolicy", func(t *testing.T) {
+		proxies := []Proxy{&mockProxy{id: 1}, &mockProxy{id: 2}}
+
+		cfg := PoolConfig{HealthPolicy: NewFailureRatePolicy(time.Minute, 0.5, 2), CooldownWindow: time.Minute}
+
+		pool := NewPool(proxies, cfg)
+		assert.Len(t, pool.healthyEntries(), 2)
+
+		pool.entries[0].Stats().RecordSuccess()
+		pool.entries[0].Stats().RecordFailed()
+		pool.entries[0].Stats().RecordSuccess()
+		pool.entries[0].Stats().RecordFailed()
+		pool.entries[0].Stats().RecordFailed()
+
+		healthy := pool.healthyEntries()
+		assert.Len(t, healthy, 1)
+		assert.Equal(t, pool.entries[1], healthy[0])
+		assert.Equal(t, CircuitOpen, pool.entries[0].State())
 	})
 
 	t.Run("DefaultPoolConfigValues", func(t *testing.T) {
@@ -26,6 +48,12 @@ func TestProxyPool(t *testing.T) {
 
 		assert.Equal(t, int64(3), cfg.MaxFails)
 		assert.Equal(t, 30*time.Second, cfg.CooldownWindow)
+		assert.Equal(t, int64(5), cfg.CooldownResetSuccesses)
+		assert.Nil(t, cfg.CooldownBackoff)
+		assert.Nil(t, cfg.HealthPolicy)
+		assert.Equal(t, time.Minute, cfg.StatsWindow)
+		assert.Equal(t, int64(1), cfg.HalfOpenMaxRequests)
+		assert.Equal(t, 1.0, cfg.HalfOpenSuccessRatio)
 		assert.NotNil(t, cfg.Selector)
 
 		_, ok := cfg.Selector.(*RoundRobinSelector)
@@ -122,4 +150,115 @@ func TestProxyPool(t *testing.T) {
 
 		assert.Equal(t, uint64(1), selector.counter.Load())
 	})
+	t.Run("PickSkipsHalfOpenEntryWithoutPermits", func(t *testing.T) {
+		proxies := []Proxy{&mockProxy{id: 1}, &mockProxy{id: 2}}
+
+		cfg := PoolConfig{MaxFails: 1, CooldownWindow: 10 * time.Millisecond, Selector: &RoundRobinSelector{}}
+
+		pool := NewPool(proxies, cfg)
+		pool.entries[1].Stats().RecordFailed()
+
+		time.Sleep(15 * time.Millisecond)
+
+		counts := make(map[int]int)
+		for i := 0; i < 10; i++ {
+			entry, err := pool.Pick()
+			assert.NoError(t, err)
+			counts[entry.Proxy().(*mockProxy).id]++
+		}
+
+		assert.Equal(t, 1, counts[2], "half-open entry should receive exactly one trial request")
+		assert.Equal(t, 9, counts[1])
+		assert.Equal(t, CircuitHalfOpen, pool.entries[1].State())
+	})
+	t.Run("EntriesReturnsSnapshot", func(t *testing.T) {
+		proxies := []Proxy{&mockProxy{id: 1}, &mockProxy{id: 2}}
+
+		pool := NewPool(proxies, PoolConfig{})
+
+		entries := pool.Entries()
+		assert.Len(t, entries, 2)
+		assert.Equal(t, proxies[0], entries[0].Proxy())
+
+		entries[0] = nil
+		assert.NotNil(t, pool.entries[0])
+	})
+	t.Run("AddProxiesToRunningPool", func(t *testing.T) {
+		pool := NewPool([]Proxy{&mockProxy{id: 1}}, PoolConfig{SlowStart: time.Hour, StatsWindow: time.Hour})
+		assert.Equal(t, 1.0, pool.entries[0].SlowStartFactor(), "initial entries do not slow start")
+
+		added := pool.Add(&mockProxy{id: 2}, &mockProxy{id: 3})
+		assert.Len(t, added, 2)
+		assert.Len(t, pool.Entries(), 3)
+
+		assert.Equal(t, added[0], pool.entries[1])
+		assert.InDelta(t, slowStartMinFactor, added[0].SlowStartFactor(), 0.01)
+		assert.Equal(t, int64(time.Hour/statsBuckets), added[0].stats.recent.bucketWidth())
+	})
+
+	t.Run("RemoveProxiesFromRunningPool", func(t *testing.T) {
+		selector := &SmoothWeightedSelector{}
+		policy := NewFailureRatePolicy(time.Minute, 0.5, 1)
+
+		pool := NewPool([]Proxy{&mockProxy{id: 1}, &mockProxy{id: 2}}, PoolConfig{Selector: selector, HealthPolicy: NewAndPolicy(policy)})
+		entries := pool.Entries()
+
+		for range 4 {
+			entry, err := pool.Pick()
+			assert.NoError(t, err)
+			assert.True(t, policy.Healthy(entry, time.Time{}))
+		}
+
+		assert.Equal(t, 1, pool.Remove(entries[0], newEntry(&mockProxy{id: 3})))
+		assert.Equal(t, 0, pool.Remove(entries[0]))
+
+		assert.Equal(t, []*Entry{entries[1]}, pool.Entries())
+		assert.True(t, entries[0].Removed())
+		assert.False(t, entries[1].Removed())
+
+		for range 4 {
+			entry, err := pool.Pick()
+			assert.NoError(t, err)
+			assert.Equal(t, entries[1], entry)
+		}
+
+		assert.NotContains(t, selector.current, entries[0])
+
+		_, tracked := policy.windows.Load(entries[0])
+		assert.False(t, tracked)
+	})
+
+	t.Run("RemoveFromStateChangeCallback", func(t *testing.T) {
+		var pool *Pool
+		pool = NewPool([]Proxy{&mockProxy{id: 1}, &mockProxy{id: 2}}, PoolConfig{
+			MaxFails:       1,
+			CooldownWindow: time.Minute,
+			OnStateChange: func(entry *Entry, _, to CircuitState) {
+				if to == CircuitOpen {
+					pool.Remove(entry)
+					pool.Add(&mockProxy{id: 3})
+				}
+			},
+		})
+
+		entries := pool.Entries()
+		entries[0].Stats().RecordFailed()
+
+		done := make(chan *Entry)
+		go func() {
+			entry, err := pool.Pick()
+			assert.NoError(t, err)
+			done <- entry
+		}()
+
+		select {
+		case entry := <-done:
+			assert.Equal(t, entries[1], entry)
+		case <-time.After(time.Second):
+			t.Fatal("Pick deadlocked on a callback changing the pool")
+		}
+
+		assert.True(t, entries[0].Removed())
+		assert.Len(t, pool.Entries(), 2)
+	})
 }
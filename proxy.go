@@ -1,7 +1,149 @@
 package client
 
-import "github.com/valyala/fasthttp"
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
 
 type Proxy interface {
 	Dial() fasthttp.DialFunc
 }
+
+// ContextDialer is implemented by proxies whose dials follow a context:
+// cancelling it or reaching its deadline aborts the dial promptly, including a
+// CONNECT or SOCKS handshake the proxy never answers.
+//
+// Proxies only tunnel TCP, so network must be "tcp", "tcp4" or "tcp6".
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// AsContextDialer returns proxy as a ContextDialer. All proxies of this package
+// implement it directly; any other Proxy is adapted by running its Dial in the
+// background, abandoning it when ctx ends first and closing the connection it
+// eventually yields.
+func AsContextDialer(proxy Proxy) ContextDialer {
+	if dialer, ok := proxy.(ContextDialer); ok {
+		return dialer
+	}
+
+	return dialAdapter{proxy: proxy}
+}
+
+type dialAdapter struct {
+	proxy Proxy
+}
+
+func (a dialAdapter) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	if ctx.Done() == nil {
+		return a.proxy.Dial()(addr)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		conn, err := a.proxy.Dial()(addr)
+		done <- result{conn: conn, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.conn, res.err
+	case <-ctx.Done():
+		go func() {
+			if res := <-done; res.conn != nil {
+				_ = res.conn.Close()
+			}
+		}()
+
+		return nil, ctx.Err()
+	}
+}
+
+// checkNetwork rejects networks other than TCP, the only one proxies can tunnel.
+func checkNetwork(network string) error {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return nil
+	default:
+		return fmt.Errorf("unsupported network '%s': proxies only tunnel TCP", network)
+	}
+}
+
+// checkAddr rejects target addresses that are not a host and a numeric port.
+func checkAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port '%s' in address '%s'", port, addr)
+	}
+
+	return nil
+}
+
+// resolveIP returns an address of host, which may be an IP literal, in the
+// family network names: "ip4" for IPv4 only, or "ip" for either, IPv4 preferred.
+func resolveIP(ctx context.Context, network, host string) (net.IP, error) {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = net.DefaultResolver.LookupIP(ctx, network, host); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ip := range ips {
+		if v4 := ip.To4(); v4 != nil {
+			return v4, nil
+		}
+	}
+
+	if network == "ip4" || len(ips) == 0 {
+		return nil, fmt.Errorf("no %s address found for host '%s'", network, host)
+	}
+
+	return ips[0], nil
+}
+
+// handshakeContext runs handshake over conn, an open connection to a proxy,
+// closing conn to abort it as soon as ctx ends. If the handshake fails conn is
+// closed, and if ctx ended first its error is returned instead.
+func handshakeContext(ctx context.Context, conn net.Conn, handshake func(conn net.Conn) (net.Conn, error)) (net.Conn, error) {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+
+	tunnel, err := handshake(conn)
+	if !stop() {
+		if err == nil {
+			_ = tunnel.Close()
+		}
+
+		_ = conn.Close()
+		return nil, ctx.Err()
+	}
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return tunnel, nil
+}
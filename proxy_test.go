@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newHungProxyListener accepts connections and never answers, like a proxy stuck
+// in its handshake. Every accepted connection is sent on the returned channel.
+func newHungProxyListener(t *testing.T) (net.Listener, chan net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	accepted := make(chan net.Conn, 8)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			accepted <- conn
+		}
+	}()
+
+	return listener, accepted
+}
+
+// assertClosedByPeer checks that the peer of conn has closed the connection.
+func assertClosedByPeer(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 512)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			var netErr net.Error
+			assert.False(t, errors.As(err, &netErr) && netErr.Timeout(), "connection was not closed")
+			return
+		}
+	}
+}
+
+func TestAsContextDialer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ReturnsNativeImplementation", func(t *testing.T) {
+		direct, _ := NewDirectProxy("", 0)
+
+		assert.Same(t, direct, AsContextDialer(direct))
+	})
+
+	t.Run("AdapterDials", func(t *testing.T) {
+		proxy := &dialFuncProxy{dial: func(addr string) (net.Conn, error) {
+			client, server := net.Pipe()
+			_ = server.Close()
+			return client, nil
+		}}
+
+		conn, err := AsContextDialer(proxy).DialContext(context.Background(), "tcp", "example.com:80")
+		assert.NoError(t, err)
+		assert.NotNil(t, conn)
+		assert.Equal(t, 1, proxy.calls)
+	})
+
+	t.Run("AdapterAbandonsSlowDial", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer server.Close()
+
+		release := make(chan struct{})
+		proxy := &dialFuncProxy{dial: func(addr string) (net.Conn, error) {
+			<-release
+			return client, nil
+		}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		conn, err := AsContextDialer(proxy).DialContext(ctx, "tcp", "example.com:80")
+		assert.Nil(t, conn)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// The connection the abandoned dial eventually yields is closed.
+		close(release)
+		_, err = server.Read(make([]byte, 1))
+		assert.Error(t, err)
+	})
+
+	t.Run("AdapterRejectsNonTCP", func(t *testing.T) {
+		conn, err := AsContextDialer(&mockProxy{id: 1}).DialContext(context.Background(), "udp", "example.com:53")
+
+		assert.Nil(t, conn)
+		assert.Error(t, err)
+	})
+}
+
+func TestProxyDialContext(t *testing.T) {
+	t.Parallel()
+
+	listener, accepted := newHungProxyListener(t)
+	defer listener.Close()
+
+	proxyAddr := listener.Addr().String()
+
+	httpProxy, _ := NewHTTPProxy("http://"+proxyAddr, 0)
+	socks4Proxy, _ := NewSOCKS4Proxy("socks4a://"+proxyAddr, 0)
+	socks5Proxy, _ := NewSOCKS5Proxy("socks5h://"+proxyAddr, 0)
+	chainProxy, _ := NewChainProxy(httpProxy, socks5Proxy)
+	failoverProxy := NewFailoverProxy(httpProxy, socks5Proxy)
+
+	proxies := []ContextDialer{httpProxy, socks4Proxy, socks5Proxy, chainProxy, failoverProxy}
+
+	for _, proxy := range proxies {
+		t.Run(fmt.Sprintf("%T", proxy), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			start := time.Now()
+			conn, err := proxy.DialContext(ctx, "tcp", "example.com:80")
+
+			assert.Nil(t, conn)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+			assertClosedByPeer(t, <-accepted)
+		})
+	}
+
+	t.Run("UnsupportedNetwork", func(t *testing.T) {
+		for _, proxy := range proxies[:4] {
+			conn, err := proxy.DialContext(context.Background(), "udp", "example.com:53")
+
+			assert.Nil(t, conn)
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestResolveIP(t *testing.T) {
+	t.Parallel()
+
+	t.Run("IPv4Only", func(t *testing.T) {
+		ip, err := resolveIP(context.Background(), "ip4", "localhost")
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", ip.String())
+
+		_, err = resolveIP(context.Background(), "ip4", "::1")
+		assert.ErrorContains(t, err, "no ip4 address")
+	})
+
+	t.Run("AnyFamilyPrefersIPv4", func(t *testing.T) {
+		ip, err := resolveIP(context.Background(), "ip", "127.0.0.1")
+		assert.NoError(t, err)
+		assert.Len(t, ip, net.IPv4len)
+
+		ip, err = resolveIP(context.Background(), "ip", "::1")
+		assert.NoError(t, err)
+		assert.Equal(t, "::1", ip.String())
+	})
+}
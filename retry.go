@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// retryer decides whether and when a failed request is sent again.
+//
+// Client and RoundTripper share it, so the fasthttp and net/http paths retry the
+// same results: those isFailure reports, which are also the ones recorded as
+// failures in Stats.
+type retryer struct {
+	maxRetries int
+	backoff    Backoff
+}
+
+// do calls attempt until it reports no reason to retry or the retries are used
+// up, and returns the error of the last attempt. Only replayable requests are
+// retried. Between attempts, do waits as long as the backoff says; if ctx ends
+// meanwhile, it returns ctx.Err().
+func (r retryer) do(ctx context.Context, replayable bool, attempt func() (retry bool, err error)) error {
+	var previous time.Duration
+
+	for n := 0; ; n++ {
+		retry, err := attempt()
+		if !retry || !replayable || n >= r.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		if r.backoff == nil {
+			continue
+		}
+
+		delay := nextDelay(r.backoff, int64(n), previous)
+		previous = delay
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// isIdempotent reports whether a request with the given method can be sent again
+// without side effects, as defined by RFC 9110 section 9.2.2.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// transport is an http.Transport dialing through a single proxy. It counts the
+// requests in flight, until their response body is read or closed, so the Client
+// knows when it is no longer in use.
+type transport struct {
+	*http.Transport
+	pending atomic.Int64
+}
+
+// pendingBody is a response body that ends its request's pending count
+// once it is read to the end or closed.
+type pendingBody struct {
+	io.ReadCloser
+	done func()
+	once sync.Once
+}
+
+func (b *pendingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.done)
+	}
+
+	return n, err
+}
+
+func (b *pendingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+
+	return err
+}
+
+func (t *transport) PendingRequests() int {
+	return int(t.pending.Load())
+}
+
+func (c *Client) newTransport(key hostKey) *transport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = nil
+	base.DialContext = AsContextDialer(key.proxy).DialContext
+	base.MaxConnsPerHost = c.cfg.MaxConnsPerHost
+	base.IdleConnTimeout = c.cfg.MaxIdleConnDuration
+
+	return &transport{Transport: base}
+}
+
+// RoundTrip implements http.RoundTripper, so that net/http based code shares the
+// routing, proxy rotation and health tracking of Do:
+//
+//	httpClient := &http.Client{Transport: client}
+//
+// Each request goes along the route Do would choose and its outcome is recorded in
+// the Stats of the Pool entry it went through. Failures are retried like in Do;
+// requests with a body are retried only if GetBody is set. Deadlines and
+// cancellation come from the request context; a cancelled request is not recorded
+// in Stats. ReadTimeout and WriteTimeout do not apply.
+//
+// As with http.Transport, a dial abandoned by a cancelled request goes on in the
+// background, so that a later request may use its connection, until it completes,
+// the proxy's own timeout expires or CloseIdleConnections is called.
+func (c *Client) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	replayable := isIdempotent(method) && (!hasBody || req.GetBody != nil)
+
+	var res *http.Response
+	attempts := 0
+
+	err := c.retry.do(ctx, replayable, func() (bool, error) {
+		if res != nil {
+			_ = res.Body.Close()
+			res = nil
+		}
+
+		attemptReq := req
+		if attempts > 0 && hasBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return false, err
+			}
+
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		attempts++
+
+		var retry bool
+		var err error
+		res, retry, err = c.roundTrip(attemptReq)
+
+		return retry, err
+	})
+
+	if err != nil {
+		if res != nil {
+			_ = res.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// roundTrip sends req once and reports whether it failed in a way worth retrying.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, bool, error) {
+	isTLS, err := isTLSScheme(req.URL.Scheme)
+	if err == nil && req.URL.Host == "" {
+		err = errors.New("missing host in request URL")
+	}
+
+	var entry *Entry
+	var proxy Proxy
+	if err == nil {
+		entry, proxy, err = c.route(req.URL.Scheme, req.URL.Host, req.URL.String())
+	}
+
+	if err != nil {
+		// A RoundTripper must close the request body, even on errors.
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		return nil, false, err
+	}
+
+	transport := c.transports.get(hostKey{entry: entry, proxy: proxy, host: req.URL.Host, isTLS: isTLS})
+
+	transport.pending.Add(1)
+	start := time.Now()
+	res, err := transport.RoundTrip(req)
+
+	if err == nil && res.Body != http.NoBody {
+		res.Body = &pendingBody{ReadCloser: res.Body, done: func() { transport.pending.Add(-1) }}
+	} else {
+		transport.pending.Add(-1)
+	}
+
+	statusCode := 0
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+
+	if entry != nil && (err == nil || req.Context().Err() == nil) {
+		recordResult(entry.Stats(), err, statusCode, time.Since(start))
+	}
+
+	return res, isFailure(err, statusCode), err
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var failures atomic.Int64
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append([]byte(r.Method+" "), body...))
+	}))
+	defer targetServer.Close()
+
+	proxyServer, tunnels := newConnectProxyServer(t)
+	defer proxyServer.Close()
+
+	get := func(t *testing.T, httpClient *http.Client, uri string) (int, string) {
+		t.Helper()
+
+		res, err := httpClient.Get(uri)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+
+		return res.StatusCode, string(body)
+	}
+
+	t.Run("RequestThroughPoolRecordsStats", func(t *testing.T) {
+		proxy, err := NewHTTPProxy(proxyServer.URL, time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{proxy}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool})
+		assert.NoError(t, err)
+
+		httpClient := &http.Client{Transport: client}
+		before := tunnels.Load()
+
+		for range 3 {
+			status, body := get(t, httpClient, targetServer.URL)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "GET ", body)
+		}
+
+		assert.Equal(t, before+1, tunnels.Load(), "the tunnel is reused")
+		assert.Equal(t, int64(3), pool.Entries()[0].Stats().SuccessCount())
+		assert.Equal(t, 1, client.transports.len())
+
+		httpClient.CloseIdleConnections()
+		assert.Equal(t, 0, client.transports.len())
+	})
+
+	t.Run("PendingUntilBodyClosed", func(t *testing.T) {
+		client, err := NewClient(ClientConfig{})
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, targetServer.URL, nil)
+		assert.NoError(t, err)
+
+		res, err := client.RoundTrip(req)
+		assert.NoError(t, err)
+
+		transport := client.transports.get(hostKey{proxy: client.direct, host: targetServer.Listener.Addr().String()})
+		assert.Equal(t, 1, transport.PendingRequests(), "the body is still to be read")
+
+		_, _ = io.ReadAll(res.Body)
+		assert.Equal(t, 0, transport.PendingRequests())
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 0, transport.PendingRequests())
+	})
+
+	t.Run("RetriesThroughNextProxy", func(t *testing.T) {
+		dead, err := NewHTTPProxy("http://127.0.0.1:1", time.Second)
+		assert.NoError(t, err)
+
+		proxy, err := NewHTTPProxy(proxyServer.URL, time.Second)
+		assert.NoError(t, err)
+
+		// RoundRobinSelector starts at the second entry, so the dead proxy goes first.
+		pool := NewPool([]Proxy{proxy, dead}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 1})
+		assert.NoError(t, err)
+
+		status, _ := get(t, &http.Client{Transport: client}, targetServer.URL)
+		assert.Equal(t, http.StatusOK, status)
+
+		entries := pool.Entries()
+		assert.Equal(t, int64(1), entries[1].Stats().Failures())
+		assert.Equal(t, int64(1), entries[0].Stats().SuccessCount())
+	})
+
+	t.Run("RequestBodyReplayedOnRetry", func(t *testing.T) {
+		direct, err := NewDirectProxy("", time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{direct}, PoolConfig{MaxFails: 10})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 2})
+		assert.NoError(t, err)
+
+		failures.Store(2)
+
+		req, err := http.NewRequest(http.MethodPut, targetServer.URL+"/flaky", strings.NewReader("payload"))
+		assert.NoError(t, err)
+
+		res, err := client.RoundTrip(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+
+		body, _ := io.ReadAll(res.Body)
+		assert.Equal(t, "PUT payload", string(body))
+		assert.Equal(t, int64(2), pool.Entries()[0].Stats().Failures())
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().SuccessCount())
+	})
+
+	t.Run("NonIdempotentRequestNotRetried", func(t *testing.T) {
+		direct, err := NewDirectProxy("", time.Second)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{direct}, PoolConfig{MaxFails: 10})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 2})
+		assert.NoError(t, err)
+
+		failures.Store(1)
+
+		res, err := (&http.Client{Transport: client}).Post(targetServer.URL+"/flaky", "text/plain", strings.NewReader("payload"))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, int64(1), pool.Entries()[0].Stats().Failures())
+	})
+
+	t.Run("CancelledRequestNotRecorded", func(t *testing.T) {
+		listener, accepted := newHungProxyListener(t)
+		defer listener.Close()
+
+		hung, err := NewHTTPProxy("http://"+listener.Addr().String(), 0)
+		assert.NoError(t, err)
+
+		pool := NewPool([]Proxy{hung}, PoolConfig{})
+		client, err := NewClient(ClientConfig{Pool: pool, MaxRetries: 1})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetServer.URL, nil)
+		assert.NoError(t, err)
+
+		start := time.Now()
+		_, err = client.RoundTrip(req)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, int64(0), pool.Entries()[0].Stats().Failures())
+
+		client.CloseIdleConnections()
+		assertClosedByPeer(t, <-accepted)
+	})
+
+	t.Run("UnsupportedScheme", func(t *testing.T) {
+		client, err := NewClient(ClientConfig{})
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, "ftp://127.0.0.1/file", nil)
+		assert.NoError(t, err)
+
+		_, err = client.RoundTrip(req)
+		assert.ErrorContains(t, err, "unsupported protocol")
+	})
+}
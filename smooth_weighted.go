@@ -0,0 +1,73 @@
+package client
+
+import "sync"
+
+// SmoothWeightedSelector distributes requests across entries proportionally to their
+// static weights (see Entry.SetStaticWeight) in a deterministic, evenly interleaved order.
+//
+// It implements the smooth weighted round-robin algorithm used by nginx: on every
+// pick each candidate's current weight grows by its static weight, the candidate with
+// the highest current weight wins and its current weight is reduced by the total.
+// With weights 5, 1, 1 this yields a, a, b, a, c, a, a rather than a, a, a, a, a, b, c.
+//
+// An entry within its slow-start window takes part with its static weight scaled
+// by Entry.SlowStartFactor, so it ramps up to its full share gradually.
+//
+// Only the entries offered by the pool take part in a pick, so a quarantined entry
+// simply stops accumulating weight and the remaining ones keep their proportions.
+// Current weights stay bounded by the total weight, so an entry coming back from
+// quarantine rejoins the rotation without a burst.
+//
+// The zero value is ready to use and SmoothWeightedSelector is safe for concurrent use.
+type SmoothWeightedSelector struct {
+	mutex   sync.Mutex
+	current map[*Entry]float64
+}
+
+// Select returns the next entry in smooth weighted round-robin order.
+// Entries with a static weight of 0 are never picked, unless every entry
+// has weight 0, in which case the first one is returned.
+func (s *SmoothWeightedSelector) Select(entries []*Entry) *Entry {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.current == nil {
+		s.current = make(map[*Entry]float64, len(entries))
+	}
+
+	var best *Entry
+	var total, bestWeight float64
+
+	for _, entry := range entries {
+		if entry.StaticWeight() <= 0 {
+			continue
+		}
+
+		weight := float64(entry.StaticWeight()) * entry.SlowStartFactor()
+
+		total += weight
+		s.current[entry] += weight
+
+		if best == nil || s.current[entry] > bestWeight {
+			best, bestWeight = entry, s.current[entry]
+		}
+	}
+
+	if best == nil {
+		return entries[0]
+	}
+
+	s.current[best] -= total
+	return best
+}
+
+func (s *SmoothWeightedSelector) forget(entry *Entry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.current, entry)
+}
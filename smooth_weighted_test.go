@@ -0,0 +1,125 @@
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmoothWeighted(t *testing.T) {
+	t.Parallel()
+
+	ids := func(selector *SmoothWeightedSelector, entries []*Entry, n int) []int {
+		result := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			result = append(result, selector.Select(entries).Proxy().(*mockProxy).id)
+		}
+
+		return result
+	}
+
+	t.Run("EmptyEntries", func(t *testing.T) {
+		selector := &SmoothWeightedSelector{}
+		assert.Nil(t, selector.Select(nil))
+	})
+
+	t.Run("SmoothInterleaving", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2}), newEntry(&mockProxy{id: 3})}
+		entries[0].SetStaticWeight(5)
+
+		selector := &SmoothWeightedSelector{}
+		assert.Equal(t, []int{1, 1, 2, 1, 3, 1, 1}, ids(selector, entries, 7))
+		assert.Equal(t, []int{1, 1, 2, 1, 3, 1, 1}, ids(selector, entries, 7))
+	})
+
+	t.Run("DefaultWeightIsRoundRobin", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2})}
+		assert.Equal(t, int64(1), entries[0].StaticWeight())
+
+		selector := &SmoothWeightedSelector{}
+		assert.Equal(t, []int{1, 2, 1, 2}, ids(selector, entries, 4))
+	})
+
+	t.Run("ZeroWeightIsDrained", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2})}
+		entries[0].SetStaticWeight(-3)
+		assert.Equal(t, int64(0), entries[0].StaticWeight())
+
+		selector := &SmoothWeightedSelector{}
+		assert.Equal(t, []int{2, 2, 2}, ids(selector, entries, 3))
+
+		entries[1].SetStaticWeight(0)
+		assert.Equal(t, []int{1, 1}, ids(selector, entries, 2))
+	})
+
+	t.Run("StableWhenEntryLeavesAndReturns", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2}), newEntry(&mockProxy{id: 3})}
+		entries[0].SetStaticWeight(2)
+
+		selector := &SmoothWeightedSelector{}
+		ids(selector, entries, 3)
+
+		// Entry 3 is quarantined: the others keep a 2:1 ratio.
+		counts := make(map[int]int)
+		for _, id := range ids(selector, entries[:2], 30) {
+			counts[id]++
+		}
+		assert.InDelta(t, 20, counts[1], 1)
+		assert.InDelta(t, 10, counts[2], 1)
+
+		counts = make(map[int]int)
+		for _, id := range ids(selector, entries, 40) {
+			counts[id]++
+		}
+		assert.InDelta(t, 20, counts[1], 1)
+		assert.InDelta(t, 10, counts[2], 1)
+		assert.InDelta(t, 10, counts[3], 1)
+	})
+
+	t.Run("SlowStartScalesWeight", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2})}
+		entries[1].slowStart = time.Hour
+		entries[1].warmingSinceUnix.Store(time.Now().UnixNano())
+
+		selector := &SmoothWeightedSelector{}
+
+		counts := make(map[int]int)
+		for _, id := range ids(selector, entries, 110) {
+			counts[id]++
+		}
+
+		assert.InDelta(t, 100, counts[1], 1)
+		assert.InDelta(t, 10, counts[2], 1)
+	})
+
+	t.Run("ConcurrentSelect", func(t *testing.T) {
+		entries := []*Entry{newEntry(&mockProxy{id: 1}), newEntry(&mockProxy{id: 2})}
+		entries[0].SetStaticWeight(3)
+
+		selector := &SmoothWeightedSelector{}
+
+		var mutex sync.Mutex
+		counts := make(map[int]int)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for j := 0; j < 100; j++ {
+					id := selector.Select(entries).Proxy().(*mockProxy).id
+
+					mutex.Lock()
+					counts[id]++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, map[int]int{1: 600, 2: 200}, counts)
+	})
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// socksHandshaker is the version specific part of a SOCKS proxy.
+type socksHandshaker interface {
+	// request builds the CONNECT request for addr, resolving it first if needed.
+	request(ctx context.Context, addr string) ([]byte, error)
+
+	// handshake sends request over conn, an open connection to the proxy,
+	// and reads the reply, authenticating first if the protocol requires it.
+	handshake(conn net.Conn, request []byte) error
+}
+
+// socksEndpoint holds what SOCKS4Proxy and SOCKS5Proxy share: the proxy address,
+// whether the proxy resolves hostnames, the handshake timeout, and the steps of a
+// dial around the version specific handshake.
+type socksEndpoint struct {
+	addr      string
+	remoteDNS bool
+	timeout   time.Duration
+}
+
+func (e *socksEndpoint) proxyAddr() string {
+	return e.addr
+}
+
+// dialContext connects to addr through the proxy with the handshake of h,
+// aborting as soon as ctx ends.
+func (e *socksEndpoint) dialContext(ctx context.Context, h socksHandshaker, network, addr string) (net.Conn, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	request, err := h.request(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: e.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return handshakeContext(ctx, conn, func(conn net.Conn) (net.Conn, error) {
+		return conn, e.establish(conn, h, request)
+	})
+}
+
+// tunnelWith performs the handshake of h for addr over conn, an open connection
+// to the proxy.
+func (e *socksEndpoint) tunnelWith(h socksHandshaker, conn net.Conn, addr string) (net.Conn, error) {
+	request, err := h.request(context.Background(), addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.establish(conn, h, request); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// establish sends request over conn and completes the handshake of h,
+// within the timeout if one is set.
+func (e *socksEndpoint) establish(conn net.Conn, h socksHandshaker, request []byte) error {
+	if e.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(e.timeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	return h.handshake(conn, request)
+}
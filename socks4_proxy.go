@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SOCKS4 reply codes, as sent by the proxy in the CD field of its reply.
+const (
+	SOCKS4Granted           byte = 90
+	SOCKS4Rejected          byte = 91
+	SOCKS4IdentdUnreachable byte = 92
+	SOCKS4IdentdMismatch    byte = 93
+)
+
+const (
+	socks4Version        byte = 4
+	socks4CommandConnect byte = 1
+)
+
+// SOCKS4Error reports a request the SOCKS4 proxy did not grant.
+// It unwraps to ErrSOCKS4Rejected, ErrSOCKS4IdentdUnreachable or
+// ErrSOCKS4IdentdMismatch depending on Code.
+type SOCKS4Error struct {
+	Code byte
+}
+
+func (e *SOCKS4Error) Error() string {
+	switch e.Code {
+	case SOCKS4Rejected:
+		return "socks4: request rejected or failed"
+	case SOCKS4IdentdUnreachable:
+		return "socks4: request rejected, proxy cannot reach identd on the client"
+	case SOCKS4IdentdMismatch:
+		return "socks4: request rejected, identd reported a different user id"
+	default:
+		return fmt.Sprintf("socks4: unknown reply code %d", e.Code)
+	}
+}
+
+func (e *SOCKS4Error) Unwrap() error {
+	switch e.Code {
+	case SOCKS4IdentdUnreachable:
+		return ErrSOCKS4IdentdUnreachable
+	case SOCKS4IdentdMismatch:
+		return ErrSOCKS4IdentdMismatch
+	default:
+		return ErrSOCKS4Rejected
+	}
+}
+
+// SOCKS4Proxy is a Proxy that tunnels connections through a SOCKS4 or SOCKS4a server.
+//
+// With socks4:// the target host is resolved locally and only IPv4 targets can be
+// reached. With socks4a:// hostnames are sent to the proxy, which resolves them.
+// The username of the URL, if any, is sent as the SOCKS4 user ID.
+type SOCKS4Proxy struct {
+	socksEndpoint
+	userID string
+}
+
+// NewSOCKS4Proxy creates a SOCKS4Proxy from a socks4:// or socks4a:// URL.
+// A non-zero timeout limits how long connecting to the proxy and completing the
+// handshake may take.
+func NewSOCKS4Proxy(proxyURL string, timeout time.Duration) (*SOCKS4Proxy, error) {
+	parseURL, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := strings.ToLower(parseURL.Scheme)
+	if scheme != "socks4" && scheme != "socks4a" {
+		return nil, fmt.Errorf("invalid proxy scheme: expected 'socks4://' or 'socks4a://', got '%s://'", parseURL.Scheme)
+	}
+
+	if parseURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url: missing host in '%s'", proxyURL)
+	}
+
+	addr := parseURL.Host
+	if parseURL.Port() == "" {
+		addr = net.JoinHostPort(parseURL.Hostname(), "1080")
+	}
+
+	endpoint := socksEndpoint{addr: addr, remoteDNS: scheme == "socks4a", timeout: timeout}
+	return &SOCKS4Proxy{socksEndpoint: endpoint, userID: parseURL.User.Username()}, nil
+}
+
+func (s *SOCKS4Proxy) Dial() fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		return s.DialContext(context.Background(), "tcp", addr)
+	}
+}
+
+// DialContext connects to addr through the proxy, aborting as soon as ctx ends.
+func (s *SOCKS4Proxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return s.dialContext(ctx, s, network, addr)
+}
+
+// tunnel performs the handshake for addr over conn, an open connection to the proxy.
+func (s *SOCKS4Proxy) tunnel(conn net.Conn, addr string) (net.Conn, error) {
+	return s.tunnelWith(s, conn, addr)
+}
+
+// request builds the CONNECT request for addr. Without remote DNS the host is
+// resolved here; with SOCKS4a a hostname is appended after the user ID and the
+// destination IP is set to the invalid address 0.0.0.x the protocol reserves for it.
+func (s *SOCKS4Proxy) request(ctx context.Context, addr string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks4: invalid port '%s'", portStr)
+	}
+
+	var ip net.IP
+	if parsed := net.ParseIP(host); parsed != nil {
+		if ip = parsed.To4(); ip == nil {
+			return nil, fmt.Errorf("socks4: cannot connect to IPv6 address %s", host)
+		}
+	} else if !s.remoteDNS {
+		if ip, err = resolveIP(ctx, "ip4", host); err != nil {
+			return nil, fmt.Errorf("socks4: %w", err)
+		}
+	}
+
+	request := make([]byte, 0, 9+len(s.userID)+len(host)+1)
+	request = append(request, socks4Version, socks4CommandConnect, byte(port>>8), byte(port))
+
+	if ip != nil {
+		request = append(request, ip...)
+		request = append(request, s.userID...)
+		return append(request, 0), nil
+	}
+
+	request = append(request, 0, 0, 0, 1)
+	request = append(request, s.userID...)
+	request = append(request, 0)
+	request = append(request, host...)
+	return append(request, 0), nil
+}
+
+func (s *SOCKS4Proxy) handshake(conn net.Conn, request []byte) error {
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[0] != 0 {
+		return fmt.Errorf("socks4: invalid reply version %d", reply[0])
+	}
+
+	if reply[1] != SOCKS4Granted {
+		return &SOCKS4Error{Code: reply[1]}
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// socks4Request is a CONNECT request as seen by the test SOCKS4 server.
+type socks4Request struct {
+	userID string
+	ip     net.IP
+	host   string
+	port   int
+}
+
+// newSOCKS4Server starts an in-process SOCKS4/SOCKS4a server that answers every
+// request with code and, when it grants the request, relays to the target.
+func newSOCKS4Server(t *testing.T, code byte) (net.Listener, func() []socks4Request) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	var mutex sync.Mutex
+	requests := make([]socks4Request, 0)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				header := make([]byte, 8)
+				if _, err := io.ReadFull(reader, header); err != nil {
+					return
+				}
+
+				userID, _ := reader.ReadString(0)
+				request := socks4Request{userID: userID[:len(userID)-1], ip: net.IP(header[4:8]), port: int(header[2])<<8 | int(header[3])}
+				target := request.ip.String()
+
+				if header[4] == 0 && header[5] == 0 && header[6] == 0 && header[7] != 0 {
+					host, _ := reader.ReadString(0)
+					request.host = host[:len(host)-1]
+					target = request.host
+				}
+
+				mutex.Lock()
+				requests = append(requests, request)
+				mutex.Unlock()
+
+				if code != SOCKS4Granted {
+					_, _ = conn.Write([]byte{0, code, 0, 0, 0, 0, 0, 0})
+					return
+				}
+
+				upstream, err := net.Dial("tcp", net.JoinHostPort(target, strconv.Itoa(request.port)))
+				if err != nil {
+					_, _ = conn.Write([]byte{0, SOCKS4Rejected, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer upstream.Close()
+
+				_, _ = conn.Write([]byte{0, SOCKS4Granted, 0, 0, 0, 0, 0, 0})
+
+				go func() { _, _ = io.Copy(upstream, reader) }()
+				_, _ = io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return listener, func() []socks4Request {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return append([]socks4Request(nil), requests...)
+	}
+}
+
+func TestNewSOCKS4Proxy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SOCKS4", func(t *testing.T) {
+		proxy, err := NewSOCKS4Proxy("socks4://alice@127.0.0.1:1081", time.Second)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:1081", proxy.addr)
+		assert.Equal(t, "alice", proxy.userID)
+		assert.False(t, proxy.remoteDNS)
+		assert.Equal(t, time.Second, proxy.timeout)
+	})
+
+	t.Run("SOCKS4aWithDefaultPort", func(t *testing.T) {
+		proxy, err := NewSOCKS4Proxy("socks4a://proxy.example", 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "proxy.example:1080", proxy.addr)
+		assert.Empty(t, proxy.userID)
+		assert.True(t, proxy.remoteDNS)
+	})
+
+	t.Run("InvalidScheme", func(t *testing.T) {
+		proxy, err := NewSOCKS4Proxy("socks5://127.0.0.1:1080", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+		assert.Contains(t, err.Error(), "invalid proxy scheme")
+	})
+
+	t.Run("MissingHost", func(t *testing.T) {
+		proxy, err := NewSOCKS4Proxy("socks4://", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		proxy, err := NewSOCKS4Proxy("socks4://proxy.com/bad-percent%", 0)
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+}
+
+func TestSOCKS4ProxyDial(t *testing.T) {
+	t.Parallel()
+
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("success"))
+	}))
+	defer targetServer.Close()
+
+	_, targetPort, _ := net.SplitHostPort(targetServer.Listener.Addr().String())
+
+	do := func(t *testing.T, proxy Proxy, uri string) (*fasthttp.Response, error) {
+		client := &fasthttp.Client{ReadTimeout: time.Second, WriteTimeout: time.Second, Dial: proxy.Dial()}
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		req.SetRequestURI(uri)
+
+		res := &fasthttp.Response{}
+		return res, client.Do(req, res)
+	}
+
+	t.Run("SOCKS4ResolvesLocally", func(t *testing.T) {
+		listener, requests := newSOCKS4Server(t, SOCKS4Granted)
+		defer listener.Close()
+
+		proxy, err := NewSOCKS4Proxy(fmt.Sprintf("socks4://alice@%s", listener.Addr()), time.Second)
+		assert.NoError(t, err)
+
+		res, err := do(t, proxy, "http://localhost:"+targetPort)
+		assert.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+		assert.Equal(t, "success", string(res.Body()))
+
+		received := requests()
+		assert.Len(t, received, 1)
+		assert.Equal(t, "alice", received[0].userID)
+		assert.Equal(t, "127.0.0.1", received[0].ip.String())
+		assert.Empty(t, received[0].host)
+		assert.Equal(t, targetPort, strconv.Itoa(received[0].port))
+	})
+
+	t.Run("SOCKS4aResolvesOnProxy", func(t *testing.T) {
+		listener, requests := newSOCKS4Server(t, SOCKS4Granted)
+		defer listener.Close()
+
+		proxy, err := NewSOCKS4Proxy(fmt.Sprintf("socks4a://%s", listener.Addr()), time.Second)
+		assert.NoError(t, err)
+
+		res, err := do(t, proxy, "http://localhost:"+targetPort)
+		assert.NoError(t, err)
+		assert.Equal(t, fasthttp.StatusOK, res.StatusCode())
+
+		received := requests()
+		assert.Len(t, received, 1)
+		assert.Equal(t, "localhost", received[0].host)
+		assert.Equal(t, "0.0.0.1", received[0].ip.String())
+	})
+
+	t.Run("SOCKS4aSendsIPLiteralAsIs", func(t *testing.T) {
+		listener, requests := newSOCKS4Server(t, SOCKS4Granted)
+		defer listener.Close()
+
+		proxy, err := NewSOCKS4Proxy(fmt.Sprintf("socks4a://%s", listener.Addr()), time.Second)
+		assert.NoError(t, err)
+
+		_, err = do(t, proxy, targetServer.URL)
+		assert.NoError(t, err)
+
+		received := requests()
+		assert.Len(t, received, 1)
+		assert.Empty(t, received[0].host)
+		assert.Equal(t, "127.0.0.1", received[0].ip.String())
+	})
+
+	t.Run("ReplyCodesMapToTypedErrors", func(t *testing.T) {
+		cases := map[byte]error{
+			SOCKS4Rejected:          ErrSOCKS4Rejected,
+			SOCKS4IdentdUnreachable: ErrSOCKS4IdentdUnreachable,
+			SOCKS4IdentdMismatch:    ErrSOCKS4IdentdMismatch,
+		}
+
+		for code, expected := range cases {
+			listener, _ := newSOCKS4Server(t, code)
+
+			proxy, err := NewSOCKS4Proxy(fmt.Sprintf("socks4://%s", listener.Addr()), time.Second)
+			assert.NoError(t, err)
+
+			conn, err := proxy.Dial()(targetServer.Listener.Addr().String())
+			assert.Nil(t, conn)
+			assert.ErrorIs(t, err, expected)
+
+			var socksErr *SOCKS4Error
+			assert.True(t, errors.As(err, &socksErr))
+			assert.Equal(t, code, socksErr.Code)
+
+			_ = listener.Close()
+		}
+	})
+
+	t.Run("IPv6TargetUnsupported", func(t *testing.T) {
+		proxy, err := NewSOCKS4Proxy("socks4://127.0.0.1:1", time.Second)
+		assert.NoError(t, err)
+
+		conn, err := proxy.Dial()("[::1]:80")
+		assert.Nil(t, conn)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "IPv6")
+	})
+
+	t.Run("ProxyUnreachable", func(t *testing.T) {
+		proxy, err := NewSOCKS4Proxy("socks4://127.0.0.1:1", time.Second)
+		assert.NoError(t, err)
+
+		conn, err := proxy.Dial()(targetServer.Listener.Addr().String())
+		assert.Nil(t, conn)
+		assert.Error(t, err)
+	})
+
+	t.Run("HandshakeTimeout", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				defer conn.Close()
+				time.Sleep(time.Second)
+			}
+		}()
+
+		proxy, err := NewSOCKS4Proxy(fmt.Sprintf("socks4://%s", listener.Addr()), 100*time.Millisecond)
+		assert.NoError(t, err)
+
+		start := time.Now()
+		conn, err := proxy.Dial()(targetServer.Listener.Addr().String())
+		assert.Nil(t, conn)
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+}
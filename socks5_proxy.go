@@ -1,31 +1,304 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/valyala/fasthttp"
-	"github.com/valyala/fasthttp/fasthttpproxy"
 )
 
+// SOCKS5 reply codes, as defined by RFC 1928 section 6.
+const (
+	SOCKS5Succeeded               byte = 0x00
+	SOCKS5GeneralFailure          byte = 0x01
+	SOCKS5ConnectionNotAllowed    byte = 0x02
+	SOCKS5NetworkUnreachable      byte = 0x03
+	SOCKS5HostUnreachable         byte = 0x04
+	SOCKS5ConnectionRefused       byte = 0x05
+	SOCKS5TTLExpired              byte = 0x06
+	SOCKS5CommandNotSupported     byte = 0x07
+	SOCKS5AddressTypeNotSupported byte = 0x08
+)
+
+const (
+	socks5Version         byte = 0x05
+	socks5CommandConnect  byte = 0x01
+	socks5MethodNoAuth    byte = 0x00
+	socks5MethodUserPass  byte = 0x02
+	socks5MethodNone      byte = 0xff
+	socks5UserPassVersion byte = 0x01
+	socks5AddrIPv4        byte = 0x01
+	socks5AddrDomain      byte = 0x03
+	socks5AddrIPv6        byte = 0x04
+)
+
+var socks5ReplyErrors = map[byte]error{
+	SOCKS5GeneralFailure:          ErrSOCKS5GeneralFailure,
+	SOCKS5ConnectionNotAllowed:    ErrSOCKS5ConnectionNotAllowed,
+	SOCKS5NetworkUnreachable:      ErrSOCKS5NetworkUnreachable,
+	SOCKS5HostUnreachable:         ErrSOCKS5HostUnreachable,
+	SOCKS5ConnectionRefused:       ErrSOCKS5ConnectionRefused,
+	SOCKS5TTLExpired:              ErrSOCKS5TTLExpired,
+	SOCKS5CommandNotSupported:     ErrSOCKS5CommandNotSupported,
+	SOCKS5AddressTypeNotSupported: ErrSOCKS5AddressTypeNotSupported,
+}
+
+// SOCKS5Error reports a CONNECT request the SOCKS5 proxy did not grant.
+// It unwraps to the sentinel error matching Code, such as ErrSOCKS5ConnectionRefused.
+type SOCKS5Error struct {
+	Code byte
+}
+
+func (e *SOCKS5Error) Error() string {
+	if err, ok := socks5ReplyErrors[e.Code]; ok {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("socks5: unknown reply code %d", e.Code)
+}
+
+func (e *SOCKS5Error) Unwrap() error {
+	return socks5ReplyErrors[e.Code]
+}
+
+// SOCKS5Proxy is a Proxy that tunnels connections through a SOCKS5 server.
+//
+// With socks5:// the target host is resolved locally and the proxy receives an IP
+// address; with socks5h:// the hostname is sent to the proxy, which resolves it.
+// Credentials in the URL are sent using username/password authentication (RFC 1929).
 type SOCKS5Proxy struct {
-	url string
+	socksEndpoint
+	url      string
+	username string
+	password string
+
+	// dialOnce builds dial, greeting and auth on first use,
+	// so they are not rebuilt for every connection.
+	dialOnce sync.Once
+	dial     fasthttp.DialFunc
+	greeting []byte
+	auth     []byte
 }
 
-func NewSOCKS5Proxy(proxyURL string) (*SOCKS5Proxy, error) {
+// NewSOCKS5Proxy creates a SOCKS5Proxy from a socks5:// or socks5h:// URL.
+// A non-zero timeout limits how long connecting to the proxy and completing the
+// handshake may take. Credentials, if any, need a username of 1 to 255 bytes and
+// a password of at most 255 bytes; a missing password is sent as an empty one.
+func NewSOCKS5Proxy(proxyURL string, timeout time.Duration) (*SOCKS5Proxy, error) {
 	parseURL, err := url.Parse(proxyURL)
 	if err != nil {
 		return nil, err
 	}
 
-	if !strings.HasPrefix(parseURL.Scheme, "socks5") {
-		return nil, fmt.Errorf("invalid proxy scheme: expected 'socks5://', got '%s://'", parseURL.Scheme)
+	scheme := strings.ToLower(parseURL.Scheme)
+	if scheme != "socks5" && scheme != "socks5h" {
+		return nil, fmt.Errorf("invalid proxy scheme: expected 'socks5://' or 'socks5h://', got '%s://'", parseURL.Scheme)
+	}
+
+	if parseURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url: missing host in '%s'", proxyURL)
+	}
+
+	addr := parseURL.Host
+	if parseURL.Port() == "" {
+		addr = net.JoinHostPort(parseURL.Hostname(), "1080")
+	}
+
+	endpoint := socksEndpoint{addr: addr, remoteDNS: scheme == "socks5h", timeout: timeout}
+	proxy := &SOCKS5Proxy{socksEndpoint: endpoint, url: proxyURL}
+	if parseURL.User != nil {
+		proxy.username = parseURL.User.Username()
+		proxy.password, _ = parseURL.User.Password()
+
+		// RFC 1929 encodes both with a one byte length. Like x/net/proxy, an empty
+		// password is still accepted for compatibility and sent with PLEN=0.
+		if len(proxy.username) == 0 || len(proxy.username) > 255 || len(proxy.password) > 255 {
+			return nil, fmt.Errorf("invalid proxy credentials: username must be 1-255 bytes and password at most 255 bytes")
+		}
 	}
 
-	return &SOCKS5Proxy{url: proxyURL}, nil
+	return proxy, nil
 }
 
 func (s *SOCKS5Proxy) Dial() fasthttp.DialFunc {
-	return fasthttpproxy.FasthttpSocksDialer(s.url)
+	s.dialOnce.Do(s.init)
+	return s.dial
+}
+
+// init builds the dial function and the method selection and authentication
+// messages, none of which depend on the target.
+func (s *SOCKS5Proxy) init() {
+	s.dial = func(addr string) (net.Conn, error) {
+		return s.DialContext(context.Background(), "tcp", addr)
+	}
+
+	s.greeting = []byte{socks5Version, 1, socks5MethodNoAuth}
+	if s.username == "" {
+		return
+	}
+
+	s.greeting = []byte{socks5Version, 2, socks5MethodNoAuth, socks5MethodUserPass}
+
+	s.auth = make([]byte, 0, 3+len(s.username)+len(s.password))
+	s.auth = append(s.auth, socks5UserPassVersion, byte(len(s.username)))
+	s.auth = append(s.auth, s.username...)
+	s.auth = append(s.auth, byte(len(s.password)))
+	s.auth = append(s.auth, s.password...)
+}
+
+// DialContext connects to addr through the proxy, aborting as soon as ctx ends.
+func (s *SOCKS5Proxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return s.dialContext(ctx, s, network, addr)
+}
+
+// tunnel performs the handshake for addr over conn, an open connection to the proxy.
+func (s *SOCKS5Proxy) tunnel(conn net.Conn, addr string) (net.Conn, error) {
+	return s.tunnelWith(s, conn, addr)
+}
+
+// request builds the CONNECT request for addr, resolving the host locally
+// unless the proxy is responsible for name resolution.
+func (s *SOCKS5Proxy) request(ctx context.Context, addr string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: invalid port '%s'", portStr)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil && !s.remoteDNS {
+		if ip, err = resolveIP(ctx, "ip", host); err != nil {
+			return nil, err
+		}
+	}
+
+	request := []byte{socks5Version, socks5CommandConnect, 0x00}
+	switch {
+	case ip == nil:
+		if len(host) > 255 {
+			return nil, fmt.Errorf("socks5: hostname too long: %d bytes", len(host))
+		}
+
+		request = append(request, socks5AddrDomain, byte(len(host)))
+		request = append(request, host...)
+	case ip.To4() != nil:
+		request = append(request, socks5AddrIPv4)
+		request = append(request, ip.To4()...)
+	default:
+		request = append(request, socks5AddrIPv6)
+		request = append(request, ip.To16()...)
+	}
+
+	return append(request, byte(port>>8), byte(port)), nil
+}
+
+func (s *SOCKS5Proxy) handshake(conn net.Conn, request []byte) error {
+	s.dialOnce.Do(s.init)
+
+	if _, err := conn.Write(s.greeting); err != nil {
+		return err
+	}
+
+	// buf holds every reply in turn. It fits a bound IPv6 address and port;
+	// only a bound domain name, which proxies rarely send, needs more.
+	buf := make([]byte, net.IPv6len+2)
+
+	reply := buf[:2]
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[0] != socks5Version {
+		return fmt.Errorf("socks5: invalid reply version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case socks5MethodNoAuth:
+	case socks5MethodUserPass:
+		if s.username == "" {
+			return fmt.Errorf("%w: proxy requires authentication", ErrSOCKS5AuthFailed)
+		}
+
+		if err := s.authenticate(conn, reply); err != nil {
+			return err
+		}
+	case socks5MethodNone:
+		return ErrSOCKS5NoAcceptableMethods
+	default:
+		return fmt.Errorf("socks5: proxy chose unsupported method %d", reply[1])
+	}
+
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := buf[:4]
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+
+	if header[0] != socks5Version {
+		return fmt.Errorf("socks5: invalid reply version %d", header[0])
+	}
+
+	if header[1] != SOCKS5Succeeded {
+		return &SOCKS5Error{Code: header[1]}
+	}
+
+	// Discard the bound address, which is of no use to a CONNECT client.
+	var boundLen int
+	switch header[3] {
+	case socks5AddrIPv4:
+		boundLen = net.IPv4len + 2
+	case socks5AddrIPv6:
+		boundLen = net.IPv6len + 2
+	case socks5AddrDomain:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return err
+		}
+
+		boundLen = int(buf[0]) + 2
+	default:
+		return fmt.Errorf("socks5: invalid bound address type %d", header[3])
+	}
+
+	if boundLen > len(buf) {
+		buf = make([]byte, boundLen)
+	}
+
+	_, err := io.ReadFull(conn, buf[:boundLen])
+	return err
+}
+
+// authenticate performs the username/password sub-negotiation of RFC 1929,
+// reading the two byte reply into reply.
+func (s *SOCKS5Proxy) authenticate(conn net.Conn, reply []byte) error {
+	if _, err := conn.Write(s.auth); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[0] != socks5UserPassVersion {
+		return fmt.Errorf("socks5: invalid authentication reply version %d", reply[0])
+	}
+
+	if reply[1] != 0x00 {
+		return ErrSOCKS5AuthFailed
+	}
+
+	return nil
 }
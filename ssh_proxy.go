@@ -0,0 +1,248 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig describes how to reach and authenticate to an SSH server used as a proxy.
+type SSHConfig struct {
+	// Addr is the SSH server address. The port defaults to 22.
+	Addr string
+
+	// User is the login name on the SSH server.
+	User string
+
+	// Password enables password authentication when set.
+	Password string
+
+	// PrivateKey enables public key authentication when set. It holds a PEM
+	// encoded key, decrypted with Passphrase if the key is encrypted.
+	PrivateKey []byte
+	Passphrase []byte
+
+	// KnownHostsFile is the path of a known_hosts file the server key is verified against.
+	KnownHostsFile string
+
+	// HostKeyCallback verifies the server key instead of KnownHostsFile.
+	// One of the two is required.
+	HostKeyCallback ssh.HostKeyCallback
+
+	// Timeout limits how long connecting to the server, the SSH handshake and
+	// opening a channel may take. Zero means no limit.
+	Timeout time.Duration
+}
+
+// SSHProxy is a Proxy that reaches targets through direct-tcpip channels of an
+// SSH connection, so bastion hosts can join a Pool like any other proxy.
+//
+// A single SSH connection is shared by all dials. When it breaks, the next dial
+// reconnects transparently.
+type SSHProxy struct {
+	addr    string
+	config  *ssh.ClientConfig
+	timeout time.Duration
+
+	mutex  sync.Mutex
+	client *ssh.Client
+}
+
+// NewSSHProxy creates an SSHProxy from cfg. The connection is established lazily
+// on the first dial. It fails if no authentication method or no host key
+// verification is configured, or if the key or known_hosts file cannot be read.
+func NewSSHProxy(cfg SSHConfig) (*SSHProxy, error) {
+	addr := cfg.Addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	auth := make([]ssh.AuthMethod, 0, 2)
+	if len(cfg.PrivateKey) > 0 {
+		var signer ssh.Signer
+		var err error
+		if len(cfg.Passphrase) > 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(cfg.PrivateKey, cfg.Passphrase)
+		} else {
+			signer, err = ssh.ParsePrivateKey(cfg.PrivateKey)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssh private key: %w", err)
+		}
+
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("ssh proxy %s: no authentication method configured", addr)
+	}
+
+	hostKeyCallback := cfg.HostKeyCallback
+	if hostKeyCallback == nil {
+		if cfg.KnownHostsFile == "" {
+			return nil, fmt.Errorf("ssh proxy %s: no host key verification configured", addr)
+		}
+
+		callback, err := knownhosts.New(cfg.KnownHostsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		hostKeyCallback = callback
+	}
+
+	config := &ssh.ClientConfig{User: cfg.User, Auth: auth, HostKeyCallback: hostKeyCallback, Timeout: cfg.Timeout}
+	return &SSHProxy{addr: addr, config: config, timeout: cfg.Timeout}, nil
+}
+
+func (s *SSHProxy) Dial() fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		return s.DialContext(context.Background(), "tcp", addr)
+	}
+}
+
+// DialContext opens a channel to addr over the shared SSH connection,
+// connecting first if needed, and aborts as soon as ctx ends.
+func (s *SSHProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := checkNetwork(network); err != nil {
+		return nil, err
+	}
+
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := s.open(ctx, client, addr)
+
+	// A rejected channel is the target's fault and a cancelled one the caller's.
+	// A timed out one only says the server is slow: the connection is shared by
+	// every dial, and if it is really dead, Wait returns and drops it. Anything
+	// else means the connection is broken, so retry once over a fresh one.
+	var openErr *ssh.OpenChannelError
+	if err != nil && !errors.As(err, &openErr) && !errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		s.drop(client)
+
+		if client, err = s.connect(ctx); err != nil {
+			return nil, err
+		}
+
+		channel, err = s.open(ctx, client, addr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return withDeadlines(channel), nil
+}
+
+// Close closes the shared SSH connection. A later dial opens a new one.
+func (s *SSHProxy) Close() error {
+	s.mutex.Lock()
+	client := s.client
+	s.client = nil
+	s.mutex.Unlock()
+
+	if client == nil {
+		return nil
+	}
+
+	return client.Close()
+}
+
+// connect returns the shared SSH connection, establishing it if needed.
+func (s *SSHProxy) connect(ctx context.Context) (*ssh.Client, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	dialer := &net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientConn ssh.Conn
+	var channels <-chan ssh.NewChannel
+	var requests <-chan *ssh.Request
+
+	_, err = handshakeContext(ctx, conn, func(conn net.Conn) (net.Conn, error) {
+		if s.timeout > 0 {
+			_ = conn.SetDeadline(time.Now().Add(s.timeout))
+			defer conn.SetDeadline(time.Time{})
+		}
+
+		var err error
+		clientConn, channels, requests, err = ssh.NewClientConn(conn, s.addr, s.config)
+		return conn, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := ssh.NewClient(clientConn, channels, requests)
+	s.client = client
+
+	go func() {
+		_ = client.Wait()
+		s.drop(client)
+	}()
+
+	return client, nil
+}
+
+// drop forgets client if it is still the shared connection, and closes it.
+func (s *SSHProxy) drop(client *ssh.Client) {
+	s.mutex.Lock()
+	if s.client == client {
+		s.client = nil
+	}
+	s.mutex.Unlock()
+
+	_ = client.Close()
+}
+
+func (s *SSHProxy) open(ctx context.Context, client *ssh.Client, addr string) (net.Conn, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	return client.DialContext(ctx, "tcp", addr)
+}
+
+// withDeadlines bridges an SSH channel, which does not support deadlines,
+// to one end of a net.Pipe, which does, as fasthttp relies on them.
+func withDeadlines(channel net.Conn) net.Conn {
+	inner, outer := net.Pipe()
+
+	go func() {
+		_, _ = io.Copy(channel, inner)
+		_ = channel.Close()
+	}()
+
+	go func() {
+		_, _ = io.Copy(inner, channel)
+		_ = inner.Close()
+	}()
+
+	return outer
+}
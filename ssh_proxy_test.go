@@ -0,0 +1,396 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTestServer is an in-process SSH server that serves direct-tcpip channels.
+type sshTestServer struct {
+	listener    net.Listener
+	hostKey     ssh.Signer
+	connections atomic.Int64
+
+	// openDelay holds back answering channel requests, in nanoseconds.
+	openDelay atomic.Int64
+
+	mutex sync.Mutex
+	conns []net.Conn
+}
+
+func newSSHTestServer(t *testing.T, password string, authorizedKey ssh.PublicKey) *sshTestServer {
+	t.Helper()
+
+	_, hostPrivate, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	hostKey, err := ssh.NewSignerFromKey(hostPrivate)
+	assert.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, given []byte) (*ssh.Permissions, error) {
+			if password != "" && string(given) == password {
+				return nil, nil
+			}
+
+			return nil, fmt.Errorf("password rejected for %s", meta.User())
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if authorizedKey != nil && string(key.Marshal()) == string(authorizedKey.Marshal()) {
+				return nil, nil
+			}
+
+			return nil, fmt.Errorf("key rejected for %s", meta.User())
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := &sshTestServer{listener: listener, hostKey: hostKey}
+	go server.serve(config)
+
+	return server
+}
+
+func (s *sshTestServer) serve(config *ssh.ServerConfig) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				_ = conn.Close()
+				return
+			}
+			defer serverConn.Close()
+
+			s.connections.Add(1)
+			s.mutex.Lock()
+			s.conns = append(s.conns, conn)
+			s.mutex.Unlock()
+
+			go ssh.DiscardRequests(requests)
+
+			for newChannel := range channels {
+				go func() {
+					time.Sleep(time.Duration(s.openDelay.Load()))
+					handleDirectTCPIP(newChannel)
+				}()
+			}
+		}()
+	}
+}
+
+// disconnectAll drops every established SSH connection, as a restarting bastion would.
+func (s *sshTestServer) disconnectAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+
+	s.conns = nil
+}
+
+func (s *sshTestServer) knownHosts(t *testing.T, key ssh.PublicKey) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.listener.Addr().String())}, key)
+	assert.NoError(t, os.WriteFile(path, []byte(line+"\n"), 0o600))
+
+	return path
+}
+
+func handleDirectTCPIP(newChannel ssh.NewChannel) {
+	if newChannel.ChannelType() != "direct-tcpip" {
+		_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		return
+	}
+
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	upstream, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer upstream.Close()
+
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	go ssh.DiscardRequests(requests)
+
+	go func() {
+		_, _ = io.Copy(upstream, channel)
+		_ = upstream.Close()
+	}()
+
+	_, _ = io.Copy(channel, upstream)
+}
+
+func TestNewSSHProxy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("NoAuthMethod", func(t *testing.T) {
+		proxy, err := NewSSHProxy(SSHConfig{Addr: "bastion.example", HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("NoHostKeyVerification", func(t *testing.T) {
+		proxy, err := NewSSHProxy(SSHConfig{Addr: "bastion.example", Password: "secret"})
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+		assert.Contains(t, err.Error(), "host key")
+	})
+
+	t.Run("MissingKnownHostsFile", func(t *testing.T) {
+		proxy, err := NewSSHProxy(SSHConfig{Addr: "bastion.example", Password: "secret", KnownHostsFile: filepath.Join(t.TempDir(), "missing")})
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("InvalidPrivateKey", func(t *testing.T) {
+		proxy, err := NewSSHProxy(SSHConfig{Addr: "bastion.example", PrivateKey: []byte("garbage"), HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+
+		assert.Error(t, err)
+		assert.Nil(t, proxy)
+	})
+
+	t.Run("DefaultPort", func(t *testing.T) {
+		proxy, err := NewSSHProxy(SSHConfig{Addr: "bastion.example", Password: "secret", HostKeyCallback: ssh.InsecureIgnoreHostKey()})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "bastion.example:22", proxy.addr)
+	})
+}
+
+func TestSSHProxyDial(t *testing.T) {
+	t.Parallel()
+
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("via bastion"))
+	}))
+	defer targetServer.Close()
+
+	target := targetServer.Listener.Addr().String()
+
+	_, clientPrivate, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	clientSigner, err := ssh.NewSignerFromKey(clientPrivate)
+	assert.NoError(t, err)
+
+	block, err := ssh.MarshalPrivateKey(clientPrivate, "")
+	assert.NoError(t, err)
+	clientKeyPEM := pem.EncodeToMemory(block)
+
+	t.Run("PasswordAuthWithKnownHosts", func(t *testing.T) {
+		server := newSSHTestServer(t, "secret", nil)
+		defer server.listener.Close()
+
+		proxy, err := NewSSHProxy(SSHConfig{
+			Addr:           server.listener.Addr().String(),
+			User:           "alice",
+			Password:       "secret",
+			KnownHostsFile: server.knownHosts(t, server.hostKey.PublicKey()),
+			Timeout:        time.Second,
+		})
+		assert.NoError(t, err)
+		defer proxy.Close()
+
+		client := &fasthttp.Client{ReadTimeout: time.Second, WriteTimeout: time.Second, Dial: proxy.Dial()}
+
+		req := fasthttp.AcquireRequest()
+		res := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(res)
+
+		req.SetRequestURI(targetServer.URL)
+
+		assert.NoError(t, client.Do(req, res))
+		assert.Equal(t, "via bastion", string(res.Body()))
+	})
+
+	t.Run("PublicKeyAuthReusesConnection", func(t *testing.T) {
+		server := newSSHTestServer(t, "", clientSigner.PublicKey())
+		defer server.listener.Close()
+
+		proxy, err := NewSSHProxy(SSHConfig{
+			Addr:            server.listener.Addr().String(),
+			User:            "alice",
+			PrivateKey:      clientKeyPEM,
+			HostKeyCallback: ssh.FixedHostKey(server.hostKey.PublicKey()),
+			Timeout:         time.Second,
+		})
+		assert.NoError(t, err)
+		defer proxy.Close()
+
+		for range 3 {
+			conn, err := proxy.Dial()(target)
+			assert.NoError(t, err)
+			_ = conn.Close()
+		}
+
+		assert.Equal(t, int64(1), server.connections.Load())
+	})
+
+	t.Run("ReconnectsAfterDisconnect", func(t *testing.T) {
+		server := newSSHTestServer(t, "secret", nil)
+		defer server.listener.Close()
+
+		proxy, err := NewSSHProxy(SSHConfig{
+			Addr:            server.listener.Addr().String(),
+			Password:        "secret",
+			HostKeyCallback: ssh.FixedHostKey(server.hostKey.PublicKey()),
+			Timeout:         time.Second,
+		})
+		assert.NoError(t, err)
+		defer proxy.Close()
+
+		conn, err := proxy.Dial()(target)
+		assert.NoError(t, err)
+		_ = conn.Close()
+
+		server.disconnectAll()
+
+		conn, err = proxy.Dial()(target)
+		assert.NoError(t, err)
+		_ = conn.Close()
+
+		assert.Equal(t, int64(2), server.connections.Load())
+	})
+
+	t.Run("UnknownHostKeyRejected", func(t *testing.T) {
+		server := newSSHTestServer(t, "secret", nil)
+		defer server.listener.Close()
+
+		proxy, err := NewSSHProxy(SSHConfig{
+			Addr:           server.listener.Addr().String(),
+			Password:       "secret",
+			KnownHostsFile: server.knownHosts(t, clientSigner.PublicKey()),
+		})
+		assert.NoError(t, err)
+
+		conn, err := proxy.Dial()(target)
+		assert.Nil(t, conn)
+		assert.Error(t, err)
+		assert.Equal(t, int64(0), server.connections.Load())
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		server := newSSHTestServer(t, "secret", nil)
+		defer server.listener.Close()
+
+		proxy, err := NewSSHProxy(SSHConfig{
+			Addr:            server.listener.Addr().String(),
+			Password:        "wrong",
+			HostKeyCallback: ssh.FixedHostKey(server.hostKey.PublicKey()),
+		})
+		assert.NoError(t, err)
+
+		conn, err := proxy.Dial()(target)
+		assert.Nil(t, conn)
+		assert.Error(t, err)
+	})
+
+	t.Run("RejectedChannelKeepsConnection", func(t *testing.T) {
+		server := newSSHTestServer(t, "secret", nil)
+		defer server.listener.Close()
+
+		proxy, err := NewSSHProxy(SSHConfig{
+			Addr:            server.listener.Addr().String(),
+			Password:        "secret",
+			HostKeyCallback: ssh.FixedHostKey(server.hostKey.PublicKey()),
+		})
+		assert.NoError(t, err)
+		defer proxy.Close()
+
+		conn, err := proxy.Dial()("127.0.0.1:1")
+		assert.Nil(t, conn)
+
+		var openErr *ssh.OpenChannelError
+		assert.ErrorAs(t, err, &openErr)
+
+		conn, err = proxy.Dial()(target)
+		assert.NoError(t, err)
+		_ = conn.Close()
+
+		assert.Equal(t, int64(1), server.connections.Load())
+	})
+	t.Run("SlowChannelKeepsConnection", func(t *testing.T) {
+		server := newSSHTestServer(t, "secret", nil)
+		defer server.listener.Close()
+
+		proxy, err := NewSSHProxy(SSHConfig{
+			Addr:            server.listener.Addr().String(),
+			Password:        "secret",
+			HostKeyCallback: ssh.FixedHostKey(server.hostKey.PublicKey()),
+			Timeout:         100 * time.Millisecond,
+		})
+		assert.NoError(t, err)
+		defer proxy.Close()
+
+		conn, err := proxy.Dial()(target)
+		assert.NoError(t, err)
+		_ = conn.Close()
+
+		server.openDelay.Store(int64(300 * time.Millisecond))
+
+		conn, err = proxy.Dial()(target)
+		assert.Nil(t, conn)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		server.openDelay.Store(0)
+
+		conn, err = proxy.Dial()(target)
+		assert.NoError(t, err)
+		_ = conn.Close()
+
+		assert.Equal(t, int64(1), server.connections.Load())
+	})
+}
@@ -7,6 +7,10 @@ import (
 
 const baseWeight float64 = 1.0
 
+// latencySampleSize is the number of most recent latency samples
+// kept for percentile-based health policies and latency-aware selectors.
+const latencySampleSize = 256
+
 // Stats tracks runtime metrics for a single proxy instance.
 // It is designed to be embedded in Entry and must not be copied after first use.
 //
@@ -20,54 +24,137 @@ type Stats struct {
 	// This is the value checked for quarantine: it resets on every success.
 	consecutiveFails atomic.Int64
 
+	// consecutiveSuccesses counts successes in a row since the last failure.
+	// Used to detect sustained recovery and reset quarantine escalation.
+	consecutiveSuccesses atomic.Int64
+
 	// failCount is a monotonically increasing failure counter.
 	// It is never reset and provides a full history of failures.
 	failCount atomic.Int64
 
 	successCount atomic.Int64
 
-	// totalLatencyMs accumulates response times for average calculation.
-	totalLatencyMs atomic.Int64
+	// totalLatencyNs accumulates response times in nanoseconds for average calculation.
+	totalLatencyNs atomic.Int64
 
 	// latencyCount is incremented each time RecordLatency is called.
 	// Kept separate from successCount so latency can be recorded independently.
 	latencyCount atomic.Int64
 
+	// recentLatency is a ring buffer of the latest latency samples,
+	// indexed by latencyCursor modulo its size.
+	recentLatency [latencySampleSize]latencySample
+	latencyCursor atomic.Uint64
+
+	// latencies is a lifetime histogram of response times used for percentiles.
+	latencies latencyHistogram
+
 	// lastFailedUnix stores the UnixNano timestamp of the most recent failure.
-	// Used by Entry.HealthCheck to determine if the cooldown window has elapsed.
+	// Used by the circuit breaker to determine if the cooldown window has elapsed.
 	lastFailedUnix atomic.Int64
+
+	// recent aggregates the same events as the lifetime counters above,
+	// but only over the most recent window (PoolConfig.StatsWindow).
+	recent slidingWindow
+
+	// bytesRead and bytesWritten total the traffic of connections dialed
+	// through Pool.DialContext, recorded as each connection is closed.
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+
+	// circuitState mirrors the owning Entry's circuit breaker state.
+	// It is written by Entry only and exposed here for observability.
+	circuitState atomic.Int32
 }
 
-// RecordSuccess increments the success counter and resets consecutiveFails.
+// latencySample is a single timestamped latency observation.
+// The two fields are stored independently, so recentLatencies may briefly
+// observe a mixed pair; this is acceptable for statistical use.
+//
+// seq is the cursor position the sample was recorded at, plus one, and zero
+// while the slot is being written. latencySamplesAfter checks it before and
+// after reading the fields, so it never returns a sample not written yet,
+// overwritten or torn.
+type latencySample struct {
+	seq     atomic.Uint64
+	atUnix  atomic.Int64
+	latency atomic.Int64
+}
+
+// latencyObservation is a plain copy of a latencySample.
+type latencyObservation struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// RecordSuccess increments the success counters and resets consecutiveFails.
 // Call this after every request that completes without a network-level error
 // and returns a non-retryable HTTP status.
 func (s *Stats) RecordSuccess() {
 	s.successCount.Add(1)
+	s.consecutiveSuccesses.Add(1)
 	s.consecutiveFails.Store(0)
+	s.recent.recordSuccess(time.Now())
 }
 
-// RecordFailed increments both consecutiveFails and failCount,
-// and timestamps the event. Call this on network errors, timeouts,
+// RecordFailed increments both consecutiveFails and failCount, resets
+// consecutiveSuccesses and timestamps the event. Call this on network errors, timeouts,
 // and retryable HTTP responses (5xx, 429).
 func (s *Stats) RecordFailed() {
 	s.consecutiveFails.Add(1)
+	s.consecutiveSuccesses.Store(0)
 	s.failCount.Add(1)
-	s.lastFailedUnix.Store(time.Now().UnixNano())
+
+	now := time.Now()
+	s.lastFailedUnix.Store(now.UnixNano())
+	s.recent.recordFailed(now)
 }
 
-// RecordLatency adds a latency sample in milliseconds.
+// RecordLatency adds a latency sample with full time.Duration precision.
 // Should be called alongside RecordSuccess to keep the average meaningful.
-func (s *Stats) RecordLatency(ms int64) {
-	s.totalLatencyMs.Add(ms)
+func (s *Stats) RecordLatency(latency time.Duration) {
+	s.totalLatencyNs.Add(int64(latency))
 	s.latencyCount.Add(1)
+	s.latencies.record(latency)
+
+	now := time.Now()
+	s.recent.recordLatency(now, latency)
+
+	position := s.latencyCursor.Add(1) - 1
+	sample := &s.recentLatency[position%latencySampleSize]
+	sample.seq.Store(0)
+	sample.latency.Store(int64(latency))
+	sample.atUnix.Store(now.UnixNano())
+	sample.seq.Store(position + 1)
+}
+
+// RecordBytes adds the traffic of a connection through the proxy to the byte counters.
+func (s *Stats) RecordBytes(read, written int64) {
+	s.bytesRead.Add(read)
+	s.bytesWritten.Add(written)
+}
+
+// BytesRead returns the total number of bytes recorded as read through the proxy.
+func (s *Stats) BytesRead() int64 {
+	return s.bytesRead.Load()
+}
+
+// BytesWritten returns the total number of bytes recorded as written through the proxy.
+func (s *Stats) BytesWritten() int64 {
+	return s.bytesWritten.Load()
 }
 
 // ConsecutiveFails returns the number of failures since the last success.
-// This is the primary signal used by HealthCheck to decide quarantine.
+// This is the signal ConsecutiveFailsPolicy uses to open the circuit.
 func (s *Stats) ConsecutiveFails() int64 {
 	return s.consecutiveFails.Load()
 }
 
+// ConsecutiveSuccesses returns the number of successes since the last failure.
+func (s *Stats) ConsecutiveSuccesses() int64 {
+	return s.consecutiveSuccesses.Load()
+}
+
 // SuccessCount returns the total number of successful requests ever recorded.
 func (s *Stats) SuccessCount() int64 {
 	return s.successCount.Load()
@@ -88,7 +175,100 @@ func (s *Stats) AvgLatencyMs() float64 {
 		return 0
 	}
 
-	return float64(s.totalLatencyMs.Load()) / float64(count)
+	return float64(s.totalLatencyNs.Load()) / float64(count) / float64(time.Millisecond)
+}
+
+// LatencyPercentile returns the latency below which the fraction q (0.0–1.0)
+// of all recorded samples fall. The result is accurate to within 12.5%
+// and never underestimates. Returns 0 if no samples have been recorded.
+func (s *Stats) LatencyPercentile(q float64) time.Duration {
+	return s.latencies.percentile(q)
+}
+
+// P50 returns the median latency. See LatencyPercentile.
+func (s *Stats) P50() time.Duration {
+	return s.latencies.percentile(0.50)
+}
+
+// P90 returns the 90th percentile latency. See LatencyPercentile.
+func (s *Stats) P90() time.Duration {
+	return s.latencies.percentile(0.90)
+}
+
+// P99 returns the 99th percentile latency. See LatencyPercentile.
+func (s *Stats) P99() time.Duration {
+	return s.latencies.percentile(0.99)
+}
+
+// RecentAvgLatencyMs returns the mean response time over the recent window.
+// Falls back to the lifetime average when no samples fall inside the window.
+func (s *Stats) RecentAvgLatencyMs() float64 {
+	totals := s.recent.totals(time.Now())
+
+	if totals.latencyCount == 0 {
+		return s.AvgLatencyMs()
+	}
+
+	return float64(totals.latencyNs) / float64(totals.latencyCount) / float64(time.Millisecond)
+}
+
+// recentLatencies returns a copy of the retained latency samples
+// recorded at or after since, in no particular order.
+func (s *Stats) recentLatencies(since time.Time) []time.Duration {
+	samples := make([]time.Duration, 0, min(s.latencyCursor.Load(), latencySampleSize))
+	sinceUnix := since.UnixNano()
+
+	for i := range s.recentLatency {
+		sample := &s.recentLatency[i]
+
+		at := sample.atUnix.Load()
+		if at == 0 || at < sinceUnix {
+			continue
+		}
+
+		samples = append(samples, time.Duration(sample.latency.Load()))
+	}
+
+	return samples
+}
+
+// latencySamplesAfter returns, in recording order, the retained samples recorded
+// after the given cursor position together with the cursor to pass next time.
+// Samples that were overwritten in the ring before being read are skipped; a
+// sample still being written ends the batch and is returned by the next call.
+func (s *Stats) latencySamplesAfter(cursor uint64) ([]latencyObservation, uint64) {
+	end := s.latencyCursor.Load()
+	if end > latencySampleSize {
+		cursor = max(cursor, end-latencySampleSize)
+	}
+
+	if cursor >= end {
+		return nil, end
+	}
+
+	observations := make([]latencyObservation, 0, end-cursor)
+	for i := cursor; i < end; i++ {
+		sample := &s.recentLatency[i%latencySampleSize]
+
+		seq := sample.seq.Load()
+		if seq < i+1 {
+			// Not written yet, or being overwritten: stop and retry next time.
+			return observations, i
+		}
+
+		observation := latencyObservation{
+			at:      time.Unix(0, sample.atUnix.Load()),
+			latency: time.Duration(sample.latency.Load()),
+		}
+
+		if seq != i+1 || sample.seq.Load() != seq {
+			continue
+		}
+
+		observations = append(observations, observation)
+	}
+
+	return observations, end
 }
 
 // successRate returns the fraction of successful requests in range [0.0, 1.0].
@@ -105,6 +285,20 @@ func (s *Stats) successRate() float64 {
 	return float64(success) / float64(total)
 }
 
+// recentSuccessRate returns the fraction of successful requests over the recent window.
+// Falls back to the lifetime successRate when no requests fall inside the window,
+// so an idle proxy keeps the reputation it earned earlier.
+func (s *Stats) recentSuccessRate() float64 {
+	totals := s.recent.totals(time.Now())
+	total := totals.success + totals.failures
+
+	if total == 0 {
+		return s.successRate()
+	}
+
+	return float64(totals.success) / float64(total)
+}
+
 // LastFailedTime returns the time of the most recent failure.
 // Returns zero time if the proxy has never failed.
 func (s *Stats) LastFailedTime() time.Time {
@@ -116,17 +310,34 @@ func (s *Stats) LastFailedTime() time.Time {
 	return time.Unix(0, ns)
 }
 
+// CircuitState returns the circuit breaker state of the proxy these stats belong to.
+// Stats that are not attached to an Entry always report CircuitClosed.
+func (s *Stats) CircuitState() CircuitState {
+	return CircuitState(s.circuitState.Load())
+}
+
 // Weight computes a selection score used by WeightedSelector.
 // The formula rewards both high success rate and low latency:
 //
 //	weight = successRate * (1000 / avgLatencyMs)
 //
+// Both inputs are taken from the recent window, so a proxy that was great
+// last week but has been failing for the last minutes loses its weight quickly.
+// Without recent data the lifetime values are used instead.
+//
 // A proxy with no latency data yet falls back to its success rate alone,
 // which is 1.0 for brand-new proxies — giving them a fair initial chance.
 func (s *Stats) Weight() float64 {
-	rate := s.successRate()
-	latency := s.AvgLatencyMs()
+	return weight(s.recentSuccessRate(), s.RecentAvgLatencyMs())
+}
+
+// LifetimeWeight computes the same score as Weight,
+// but from counters accumulated over the whole lifetime of the proxy.
+func (s *Stats) LifetimeWeight() float64 {
+	return weight(s.successRate(), s.AvgLatencyMs())
+}
 
+func weight(rate, latency float64) float64 {
 	if latency > 0 {
 		return rate * (1000.0 / latency)
 	}
@@ -31,8 +31,12 @@ func TestStats(t *testing.T) {
 
 		stats.RecordSuccess()
 		assert.Equal(t, int64(0), stats.ConsecutiveFails())
+		assert.Equal(t, int64(1), stats.ConsecutiveSuccesses())
 		assert.Equal(t, int64(1), stats.SuccessCount())
 		assert.Equal(t, int64(2), stats.Failures())
+
+		stats.RecordFailed()
+		assert.Equal(t, int64(0), stats.ConsecutiveSuccesses())
 	})
 
 	t.Run("RecordFailed", func(t *testing.T) {
@@ -51,12 +55,36 @@ func TestStats(t *testing.T) {
 
 	t.Run("RecordLatency", func(t *testing.T) {
 		stats := &Stats{}
-		stats.RecordLatency(50)
-		stats.RecordLatency(50)
-		stats.RecordLatency(300)
-		stats.RecordLatency(200)
+		stats.RecordLatency(50 * time.Millisecond)
+		stats.RecordLatency(50 * time.Millisecond)
+		stats.RecordLatency(300 * time.Millisecond)
+		stats.RecordLatency(200 * time.Millisecond)
 
 		assert.Equal(t, float64(150), stats.AvgLatencyMs())
+		assert.ElementsMatch(t, []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 300 * time.Millisecond, 200 * time.Millisecond}, stats.recentLatencies(time.Time{}))
+	})
+
+	t.Run("RecordBytes", func(t *testing.T) {
+		stats := &Stats{}
+		stats.RecordBytes(100, 20)
+		stats.RecordBytes(50, 0)
+
+		assert.Equal(t, int64(150), stats.BytesRead())
+		assert.Equal(t, int64(20), stats.BytesWritten())
+	})
+
+	t.Run("RecentLatenciesKeepsLatestSamples", func(t *testing.T) {
+		stats := &Stats{}
+		for i := 0; i < latencySampleSize+10; i++ {
+			stats.RecordLatency(time.Duration(i))
+		}
+
+		samples := stats.recentLatencies(time.Time{})
+		assert.Len(t, samples, latencySampleSize)
+		assert.NotContains(t, samples, time.Duration(9))
+		assert.Contains(t, samples, time.Duration(10))
+
+		assert.Empty(t, stats.recentLatencies(time.Now()))
 	})
 
 	t.Run("SuccessRate", func(t *testing.T) {
@@ -84,4 +112,92 @@ func TestStats(t *testing.T) {
 		proxyRate := stats.successRate()
 		assert.Equal(t, 0.2, math.Trunc(proxyRate*10)/10)
 	})
+	t.Run("WeightUsesRecentWindow", func(t *testing.T) {
+		stats := &Stats{}
+		stats.recent.setWindow(50 * time.Millisecond)
+
+		for i := 0; i < 8; i++ {
+			stats.RecordSuccess()
+			stats.RecordLatency(100 * time.Millisecond)
+		}
+
+		time.Sleep(60 * time.Millisecond)
+
+		stats.RecordFailed()
+		stats.RecordSuccess()
+		stats.RecordLatency(500 * time.Millisecond)
+
+		assert.Equal(t, 0.5, stats.recentSuccessRate())
+		assert.Equal(t, float64(500), stats.RecentAvgLatencyMs())
+		assert.InDelta(t, 0.5*1000.0/500.0, stats.Weight(), 1e-9)
+
+		assert.InDelta(t, 0.9*1000.0/(1300.0/9.0), stats.LifetimeWeight(), 1e-9)
+	})
+
+	t.Run("RecentViewFallsBackToLifetime", func(t *testing.T) {
+		stats := &Stats{}
+		stats.recent.setWindow(20 * time.Millisecond)
+
+		stats.RecordSuccess()
+		stats.RecordFailed()
+		stats.RecordLatency(200 * time.Millisecond)
+
+		time.Sleep(30 * time.Millisecond)
+
+		assert.Equal(t, 0.5, stats.recentSuccessRate())
+		assert.Equal(t, float64(200), stats.RecentAvgLatencyMs())
+		assert.Equal(t, stats.LifetimeWeight(), stats.Weight())
+	})
+	t.Run("SubMillisecondLatency", func(t *testing.T) {
+		stats := &Stats{}
+		stats.RecordLatency(250 * time.Microsecond)
+		stats.RecordLatency(750 * time.Microsecond)
+
+		assert.Equal(t, 0.5, stats.AvgLatencyMs())
+	})
+
+	t.Run("LatencyPercentiles", func(t *testing.T) {
+		stats := &Stats{}
+		assert.Equal(t, time.Duration(0), stats.P50())
+
+		for i := 1; i <= 100; i++ {
+			stats.RecordLatency(time.Duration(i) * time.Millisecond)
+		}
+
+		assert.InDelta(t, float64(50*time.Millisecond), float64(stats.P50()), float64(7*time.Millisecond))
+		assert.InDelta(t, float64(90*time.Millisecond), float64(stats.P90()), float64(12*time.Millisecond))
+		assert.InDelta(t, float64(99*time.Millisecond), float64(stats.P99()), float64(13*time.Millisecond))
+		assert.Equal(t, stats.P90(), stats.LatencyPercentile(0.9))
+	})
+
+	t.Run("LatencySamplesAfterSkipsUnwrittenSlots", func(t *testing.T) {
+		stats := &Stats{}
+		stats.RecordLatency(10 * time.Millisecond)
+
+		// A writer has taken the next position but not stored its sample yet.
+		stats.latencyCursor.Add(1)
+		stats.recentLatency[1].latency.Store(int64(time.Hour))
+
+		observations, cursor := stats.latencySamplesAfter(0)
+		assert.Len(t, observations, 1)
+		assert.Equal(t, 10*time.Millisecond, observations[0].latency)
+		assert.Equal(t, uint64(1), cursor, "the pending sample is read next time")
+
+		stats.recentLatency[1].atUnix.Store(time.Now().UnixNano())
+		stats.recentLatency[1].seq.Store(2)
+		stats.RecordLatency(30 * time.Millisecond)
+
+		observations, cursor = stats.latencySamplesAfter(cursor)
+		assert.Len(t, observations, 2)
+		assert.Equal(t, time.Hour, observations[0].latency)
+		assert.Equal(t, uint64(3), cursor)
+
+		// A sample overwritten before it was read is skipped.
+		stats.latencyCursor.Add(1)
+		stats.recentLatency[3].seq.Store(4 + latencySampleSize)
+
+		observations, cursor = stats.latencySamplesAfter(cursor)
+		assert.Empty(t, observations)
+		assert.Equal(t, uint64(4), cursor)
+	})
 }
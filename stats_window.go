@@ -0,0 +1,120 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// statsBuckets is the number of buckets a sliding window is split into.
+	// Older data leaves the window one bucket at a time.
+	statsBuckets = 60
+
+	defaultStatsWindow = time.Minute
+)
+
+// slidingWindow aggregates request outcomes over the most recent period of time.
+//
+// The window is a ring of fixed-width time buckets. Each bucket remembers which
+// time slot (epoch) it currently holds and is lazily reset when a write for a
+// newer slot lands on it, so no background goroutine is needed.
+//
+// Updates are lock-free. A write racing with a bucket reset may occasionally be
+// lost; this is acceptable because the window is a statistical view, not a ledger.
+type slidingWindow struct {
+	// width is the bucket width in nanoseconds. Zero means defaultStatsWindow / statsBuckets.
+	width   atomic.Int64
+	buckets [statsBuckets]statsBucket
+}
+
+type statsBucket struct {
+	epoch        atomic.Int64
+	success      atomic.Int64
+	failures     atomic.Int64
+	latencyNs    atomic.Int64
+	latencyCount atomic.Int64
+}
+
+// windowTotals is a point-in-time sum of all buckets inside the window.
+type windowTotals struct {
+	success      int64
+	failures     int64
+	latencyNs    int64
+	latencyCount int64
+}
+
+// setWindow changes the window length. It must be called before any data is recorded.
+func (w *slidingWindow) setWindow(window time.Duration) {
+	if window <= 0 {
+		window = defaultStatsWindow
+	}
+
+	w.width.Store(max(int64(window/statsBuckets), 1))
+}
+
+func (w *slidingWindow) bucketWidth() int64 {
+	if width := w.width.Load(); width > 0 {
+		return width
+	}
+
+	return int64(defaultStatsWindow / statsBuckets)
+}
+
+// bucket returns the bucket for the time slot containing now,
+// resetting it first if it still holds an older slot.
+func (w *slidingWindow) bucket(now time.Time) *statsBucket {
+	epoch := now.UnixNano() / w.bucketWidth()
+	bucket := &w.buckets[epoch%statsBuckets]
+
+	for {
+		current := bucket.epoch.Load()
+		if current >= epoch {
+			return bucket
+		}
+
+		if bucket.epoch.CompareAndSwap(current, epoch) {
+			bucket.success.Store(0)
+			bucket.failures.Store(0)
+			bucket.latencyNs.Store(0)
+			bucket.latencyCount.Store(0)
+
+			return bucket
+		}
+	}
+}
+
+func (w *slidingWindow) recordSuccess(now time.Time) {
+	w.bucket(now).success.Add(1)
+}
+
+func (w *slidingWindow) recordFailed(now time.Time) {
+	w.bucket(now).failures.Add(1)
+}
+
+func (w *slidingWindow) recordLatency(now time.Time, latency time.Duration) {
+	bucket := w.bucket(now)
+	bucket.latencyNs.Add(int64(latency))
+	bucket.latencyCount.Add(1)
+}
+
+// totals sums the buckets whose time slot still falls inside the window ending at now.
+func (w *slidingWindow) totals(now time.Time) windowTotals {
+	epoch := now.UnixNano() / w.bucketWidth()
+
+	var totals windowTotals
+	for i := range w.buckets {
+		bucket := &w.buckets[i]
+
+		bucketEpoch := bucket.epoch.Load()
+		if bucketEpoch <= epoch-statsBuckets || bucketEpoch > epoch {
+			continue
+		}
+
+		totals.success += bucket.success.Load()
+		totals.failures += bucket.failures.Load()
+		totals.latencyNs += bucket.latencyNs.Load()
+		totals.latencyCount += bucket.latencyCount.Load()
+	}
+
+	return totals
+}
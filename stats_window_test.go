@@ -0,0 +1,60 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	t.Run("DefaultBucketWidth", func(t *testing.T) {
+		window := &slidingWindow{}
+		assert.Equal(t, int64(defaultStatsWindow/statsBuckets), window.bucketWidth())
+
+		window.setWindow(-time.Second)
+		assert.Equal(t, int64(defaultStatsWindow/statsBuckets), window.bucketWidth())
+
+		window.setWindow(time.Hour)
+		assert.Equal(t, int64(time.Minute), window.bucketWidth())
+	})
+
+	t.Run("TotalsWithinWindow", func(t *testing.T) {
+		window := &slidingWindow{}
+		now := time.Now()
+
+		window.recordSuccess(now)
+		window.recordSuccess(now.Add(10 * time.Second))
+		window.recordFailed(now.Add(20 * time.Second))
+		window.recordLatency(now.Add(30*time.Second), 100*time.Millisecond)
+		window.recordLatency(now.Add(30*time.Second), 300*time.Millisecond)
+
+		totals := window.totals(now.Add(30 * time.Second))
+		assert.Equal(t, windowTotals{success: 2, failures: 1, latencyNs: int64(400 * time.Millisecond), latencyCount: 2}, totals)
+	})
+
+	t.Run("BucketsLeaveTheWindow", func(t *testing.T) {
+		window := &slidingWindow{}
+		now := time.Now()
+
+		window.recordFailed(now)
+		window.recordSuccess(now.Add(30 * time.Second))
+
+		totals := window.totals(now.Add(70 * time.Second))
+		assert.Equal(t, int64(0), totals.failures)
+		assert.Equal(t, int64(1), totals.success)
+	})
+
+	t.Run("ReusedBucketIsReset", func(t *testing.T) {
+		window := &slidingWindow{}
+		now := time.Now()
+
+		window.recordFailed(now)
+		window.recordSuccess(now.Add(defaultStatsWindow))
+
+		totals := window.totals(now.Add(defaultStatsWindow))
+		assert.Equal(t, windowTotals{success: 1}, totals)
+	})
+}
@@ -0,0 +1,87 @@
+package client
+
+// Tier is a single priority level of a TieredPool.
+type Tier struct {
+	// Pool holds the proxies of this tier.
+	Pool *Pool
+
+	// MinHealthy is the number of healthy entries the tier needs to serve traffic.
+	// Below it, Pick prefers a lower-priority tier that still meets its own
+	// threshold. Defaults to 1 if zero.
+	MinHealthy int
+}
+
+// TieredPool composes several Pools by priority, for example cheap datacenter
+// proxies as the primary tier and expensive residential proxies as fallback.
+//
+// Each tier keeps its own PoolConfig, Selector and Stats; TieredPool only decides
+// which tier serves a request. Traffic returns to a higher tier automatically as
+// soon as enough of its proxies recover.
+type TieredPool struct {
+	tiers []Tier
+}
+
+// NewTieredPool creates a TieredPool. Tiers are given in priority order:
+// the first tier is the primary one.
+func NewTieredPool(tiers ...Tier) *TieredPool {
+	normalized := make([]Tier, 0, len(tiers))
+	for _, tier := range tiers {
+		if tier.MinHealthy <= 0 {
+			tier.MinHealthy = 1
+		}
+
+		normalized = append(normalized, tier)
+	}
+
+	return &TieredPool{tiers: normalized}
+}
+
+// Pick selects an entry from the highest-priority tier that meets its MinHealthy
+// threshold and returns it together with the index of the tier that served it.
+//
+// If no tier meets its threshold, the highest-priority tier with at least one
+// healthy entry is used. If every proxy in every tier is in quarantine, Pick falls
+// back to the first non-empty tier's Pool.Pick, which selects from all its entries
+// rather than stalling. ErrProxyPoolEmpty is returned only if all tiers are empty.
+func (t *TieredPool) Pick() (*Entry, int, error) {
+	healthy := make([][]*Entry, len(t.tiers))
+	fallback := -1
+
+	for i, tier := range t.tiers {
+		entries, size := tier.Pool.snapshotHealthy()
+		if size > 0 && fallback < 0 {
+			fallback = i
+		}
+
+		if len(entries) >= tier.MinHealthy {
+			if entry := tier.Pool.selectHealthy(entries); entry != nil {
+				return entry, i, nil
+			}
+		}
+
+		healthy[i] = entries
+	}
+
+	for i, tier := range t.tiers {
+		if entry := tier.Pool.selectHealthy(healthy[i]); entry != nil {
+			return entry, i, nil
+		}
+	}
+
+	if fallback < 0 {
+		return nil, -1, ErrProxyPoolEmpty
+	}
+
+	entry, err := t.tiers[fallback].Pool.Pick()
+	return entry, fallback, err
+}
+
+// Tiers returns the number of tiers.
+func (t *TieredPool) Tiers() int {
+	return len(t.tiers)
+}
+
+// Tier returns the pool at the given priority index.
+func (t *TieredPool) Tier(index int) *Pool {
+	return t.tiers[index].Pool
+}
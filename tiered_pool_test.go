@@ -0,0 +1,129 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTieredPool(t *testing.T) {
+	t.Parallel()
+
+	newTierPool := func(ids ...int) *Pool {
+		proxies := make([]Proxy, 0, len(ids))
+		for _, id := range ids {
+			proxies = append(proxies, &mockProxy{id: id})
+		}
+
+		return NewPool(proxies, PoolConfig{MaxFails: 1, CooldownWindow: time.Minute})
+	}
+
+	quarantine := func(entries ...*Entry) {
+		for _, entry := range entries {
+			entry.Stats().RecordFailed()
+		}
+	}
+
+	t.Run("EmptyTiers", func(t *testing.T) {
+		entry, tier, err := NewTieredPool().Pick()
+		assert.Nil(t, entry)
+		assert.Equal(t, -1, tier)
+		assert.ErrorIs(t, err, ErrProxyPoolEmpty)
+
+		entry, tier, err = NewTieredPool(Tier{Pool: newTierPool()}).Pick()
+		assert.Nil(t, entry)
+		assert.Equal(t, -1, tier)
+		assert.ErrorIs(t, err, ErrProxyPoolEmpty)
+	})
+
+	t.Run("DefaultMinHealthy", func(t *testing.T) {
+		tiered := NewTieredPool(Tier{Pool: newTierPool(1)}, Tier{Pool: newTierPool(2), MinHealthy: 3})
+
+		assert.Equal(t, 2, tiered.Tiers())
+		assert.Equal(t, 1, tiered.tiers[0].MinHealthy)
+		assert.Equal(t, 3, tiered.tiers[1].MinHealthy)
+	})
+
+	t.Run("PrimaryTierServesWhenHealthy", func(t *testing.T) {
+		tiered := NewTieredPool(Tier{Pool: newTierPool(1, 2)}, Tier{Pool: newTierPool(3)})
+
+		for i := 0; i < 4; i++ {
+			entry, tier, err := tiered.Pick()
+			assert.NoError(t, err)
+			assert.Equal(t, 0, tier)
+			assert.Contains(t, []int{1, 2}, entry.Proxy().(*mockProxy).id)
+		}
+	})
+
+	t.Run("FallsThroughWhenPrimaryQuarantined", func(t *testing.T) {
+		tiered := NewTieredPool(Tier{Pool: newTierPool(1, 2)}, Tier{Pool: newTierPool(3)})
+		quarantine(tiered.Tier(0).Entries()...)
+
+		entry, tier, err := tiered.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tier)
+		assert.Equal(t, 3, entry.Proxy().(*mockProxy).id)
+	})
+
+	t.Run("FallsThroughBelowMinHealthy", func(t *testing.T) {
+		tiered := NewTieredPool(Tier{Pool: newTierPool(1, 2, 3), MinHealthy: 2}, Tier{Pool: newTierPool(4)})
+		quarantine(tiered.Tier(0).Entries()[:2]...)
+
+		entry, tier, err := tiered.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tier)
+		assert.Equal(t, 4, entry.Proxy().(*mockProxy).id)
+	})
+
+	t.Run("UsesDegradedTierWhenNoTierMeetsThreshold", func(t *testing.T) {
+		tiered := NewTieredPool(Tier{Pool: newTierPool(1, 2), MinHealthy: 2}, Tier{Pool: newTierPool(3)})
+		quarantine(tiered.Tier(0).Entries()[0])
+		quarantine(tiered.Tier(1).Entries()...)
+
+		entry, tier, err := tiered.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, tier)
+		assert.Equal(t, 2, entry.Proxy().(*mockProxy).id)
+	})
+
+	t.Run("HalfOpenEntryWithoutPermits", func(t *testing.T) {
+		pool := NewPool([]Proxy{&mockProxy{id: 1}}, PoolConfig{MaxFails: 1, CooldownWindow: 10 * time.Millisecond, Selector: &permitTakingSelector{}})
+		tiered := NewTieredPool(Tier{Pool: pool}, Tier{Pool: newTierPool()})
+		quarantine(pool.Entries()...)
+
+		time.Sleep(15 * time.Millisecond)
+
+		entry, tier, err := tiered.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, tier)
+		assert.Equal(t, 1, entry.Proxy().(*mockProxy).id)
+		assert.Equal(t, CircuitHalfOpen, entry.State())
+	})
+
+	t.Run("FallsBackToFirstTierWhenAllQuarantined", func(t *testing.T) {
+		tiered := NewTieredPool(Tier{Pool: newTierPool()}, Tier{Pool: newTierPool(1)}, Tier{Pool: newTierPool(2)})
+		quarantine(tiered.Tier(1).Entries()...)
+		quarantine(tiered.Tier(2).Entries()...)
+
+		entry, tier, err := tiered.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tier)
+		assert.Equal(t, 1, entry.Proxy().(*mockProxy).id)
+	})
+}
+
+// permitTakingSelector selects the first entry after taking its trial permits,
+// as if a concurrent caller had picked the entry in between.
+type permitTakingSelector struct{}
+
+func (s *permitTakingSelector) Select(entries []*Entry) *Entry {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	for entries[0].breaker.acquire(1) {
+	}
+
+	return entries[0]
+}
@@ -11,29 +11,49 @@ import "math/rand/v2"
 // a single proxy just because it has the highest score.
 type WeightedSelector struct {
 	randFloat64 func() float64
+
+	// weight scores an entry. Defaults to Stats.Weight if nil.
+	weight func(stats *Stats) float64
 }
 
 // NewWeightedRandom initializes a new selector with the standard
-// math/rand generator.
+// math/rand generator. Entries are scored from their recent Stats window.
 func NewWeightedRandom() *WeightedSelector {
-	return &WeightedSelector{randFloat64: rand.Float64}
+	return &WeightedSelector{randFloat64: rand.Float64, weight: (*Stats).Weight}
+}
+
+// NewLifetimeWeightedRandom initializes a new selector that scores entries
+// from their lifetime Stats instead of the recent window.
+func NewLifetimeWeightedRandom() *WeightedSelector {
+	return &WeightedSelector{randFloat64: rand.Float64, weight: (*Stats).LifetimeWeight}
 }
 
-// Select returns a randomly chosen entry weighted by Stats.Weight.
+// Select returns a randomly chosen entry weighted by the configured score,
+// scaled down for entries still within their slow-start window.
 // Entries with weight 0 are effectively excluded from selection.
 func (w *WeightedSelector) Select(entries []*Entry) *Entry {
 	if len(entries) == 0 {
 		return nil
 	}
 
+	weightFunc := w.weight
+	if weightFunc == nil {
+		weightFunc = (*Stats).Weight
+	}
+
+	// Weights are computed once, so both passes see the same values
+	// even if Stats are updated concurrently.
+	weights := make([]float64, len(entries))
+
 	var sum float64
-	for _, entry := range entries {
-		sum += entry.stats.Weight()
+	for i, entry := range entries {
+		weights[i] = weightFunc(&entry.stats) * entry.SlowStartFactor()
+		sum += weights[i]
 	}
 
 	r := w.randFloat64() * sum
-	for _, entry := range entries {
-		r -= entry.stats.Weight()
+	for i, entry := range entries {
+		r -= weights[i]
 		if r <= 0 {
 			return entry
 		}
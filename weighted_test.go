@@ -2,6 +2,7 @@ package client
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,11 +24,11 @@ func TestWeightedRandom(t *testing.T) {
 
 		first := &Entry{proxy: &mockProxy{id: 1}}
 		first.stats.RecordSuccess()
-		first.stats.RecordLatency(100)
+		first.stats.RecordLatency(100 * time.Millisecond)
 
 		second := &Entry{proxy: &mockProxy{id: 2}}
 		second.stats.RecordSuccess()
-		second.stats.RecordLatency(500)
+		second.stats.RecordLatency(500 * time.Millisecond)
 
 		entries := []*Entry{first, second}
 		counts := make(map[int]int)
@@ -60,4 +61,35 @@ func TestWeightedRandom(t *testing.T) {
 		assert.NotNil(t, selected)
 		assert.Equal(t, 2, selected.proxy.(*mockProxy).id, "Should return last entry when r exceeds sum")
 	})
+	t.Run("LifetimeWeightedRandom", func(t *testing.T) {
+		weightedRandom := NewLifetimeWeightedRandom()
+		assert.NotNil(t, weightedRandom)
+		assert.NotNil(t, weightedRandom.weight)
+
+		first := &Entry{proxy: &mockProxy{id: 1}}
+		first.stats.RecordFailed()
+
+		second := &Entry{proxy: &mockProxy{id: 2}}
+		second.stats.RecordSuccess()
+
+		entries := []*Entry{first, second}
+		for i := 0; i < 100; i++ {
+			assert.Equal(t, 2, weightedRandom.Select(entries).proxy.(*mockProxy).id)
+		}
+	})
+	t.Run("SlowStartScalesWeight", func(t *testing.T) {
+		weightedRandom := &WeightedSelector{randFloat64: func() float64 { return 0.95 }}
+
+		warming := newEntry(&mockProxy{id: 1})
+		warming.slowStart = time.Hour
+		warming.warmingSinceUnix.Store(time.Now().UnixNano())
+
+		entries := []*Entry{warming, newEntry(&mockProxy{id: 2})}
+
+		// Weights are ~0.1 and 1.0, so 0.95 of the sum lands on the second entry.
+		assert.Equal(t, 2, weightedRandom.Select(entries).proxy.(*mockProxy).id)
+
+		weightedRandom.randFloat64 = func() float64 { return 0.05 }
+		assert.Equal(t, 1, weightedRandom.Select(entries).proxy.(*mockProxy).id)
+	})
 }